@@ -0,0 +1,57 @@
+package datastar
+
+import "encoding/json"
+
+// JSONCodec encodes and decodes signals.
+//
+// It's used to marshal signals sent with EventMergeSignals and to unmarshal request signals in UnmarshalSignals.
+// Default codec is StdJSONCodec, set another one with WithJSONCodec option.
+type JSONCodec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// StdJSONCodec is a JSONCodec implemented with encoding/json package. It is the default codec.
+var StdJSONCodec JSONCodec = stdJSONCodec{}
+
+type stdJSONCodec struct{}
+
+func (stdJSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdJSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// JSONCodecFuncs makes a JSONCodec from a pair of marshal and unmarshal functions.
+//
+// Use it to plug in a third-party encoder, that has compatible functions, like:
+//
+//	datastar.JSONCodecFuncs(sonic.Marshal, sonic.Unmarshal)
+//
+// Encoders with options (like encoding/json/v2) need a small wrapper:
+//
+//	datastar.JSONCodecFuncs(
+//		func(v any) ([]byte, error) { return jsonv2.Marshal(v) },
+//		func(data []byte, v any) error { return jsonv2.Unmarshal(data, v) },
+//	)
+func JSONCodecFuncs(marshal func(v any) ([]byte, error), unmarshal func(data []byte, v any) error) JSONCodec {
+	return codecFuncs{
+		marshal:   marshal,
+		unmarshal: unmarshal,
+	}
+}
+
+type codecFuncs struct {
+	marshal   func(v any) ([]byte, error)
+	unmarshal func(data []byte, v any) error
+}
+
+func (codec codecFuncs) Marshal(v any) ([]byte, error) {
+	return codec.marshal(v)
+}
+
+func (codec codecFuncs) Unmarshal(data []byte, v any) error {
+	return codec.unmarshal(data, v)
+}
@@ -0,0 +1,41 @@
+package datastar
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestJSONCodec(t *testing.T) {
+	is := is.New(t)
+
+	marshalled, unmarshalled := 0, 0
+	codec := JSONCodecFuncs(
+		func(v any) ([]byte, error) {
+			marshalled++
+			return json.Marshal(v)
+		},
+		func(data []byte, v any) error {
+			unmarshalled++
+			return json.Unmarshal(data, v)
+		},
+	)
+
+	resp := httptest.NewRecorder()
+	ds, release := New(resp, newSignalsRequest(`{"user": {"name": "john"}}`), WithJSONCodec(codec))
+	defer release()
+
+	var name string
+	is.NoErr(ds.UnmarshalSignals(&name, "user.name"))
+	is.Equal(name, "john")
+
+	var signals map[string]any
+	is.NoErr(ds.UnmarshalSignals(&signals))
+	is.Equal(unmarshalled, 2) // both path and full unmarshal should use codec
+
+	is.NoErr(ds.Send(MergeSignals(Signals{"user.name": "jane"})))
+	is.Equal(marshalled, 1) // merge signals should use codec
+	is.Equal(resp.Body.String(), "event: datastar-merge-signals\ndata: signals {\"user\":{\"name\":\"jane\"}}\n\n")
+}
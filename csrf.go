@@ -0,0 +1,251 @@
+package datastar
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/awryme/datastar-go/ds"
+)
+
+const (
+	// DefaultCSRFHeader is the default header to read csrf token from.
+	DefaultCSRFHeader = "X-CSRF-Token"
+
+	// DefaultCSRFSignal is the default signal to read csrf token from.
+	DefaultCSRFSignal = "csrf"
+
+	// DefaultCSRFCookie is the default cookie to store csrf token in.
+	DefaultCSRFCookie = "csrf_token"
+)
+
+const csrfTokenSize = 32
+
+// ErrCSRF is passed to csrf error handler when request fails csrf checks.
+var ErrCSRF = fmt.Errorf("csrf check failed")
+
+type csrfOptions struct {
+	header         string
+	signal         string
+	cookie         string
+	trustedOrigins []string
+	signalsOpts    []Option
+	errorHandler   func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// CSRFOption configures CSRF middleware.
+type CSRFOption func(opts *csrfOptions)
+
+// WithCSRFHeader sets the header to read csrf token from, DefaultCSRFHeader is used by default.
+func WithCSRFHeader(name string) CSRFOption {
+	return func(opts *csrfOptions) {
+		opts.header = name
+	}
+}
+
+// WithCSRFSignal sets the signal to read csrf token from, DefaultCSRFSignal is used by default.
+func WithCSRFSignal(name string) CSRFOption {
+	return func(opts *csrfOptions) {
+		opts.signal = name
+	}
+}
+
+// WithCSRFCookie sets the cookie to store csrf token in, DefaultCSRFCookie is used by default.
+func WithCSRFCookie(name string) CSRFOption {
+	return func(opts *csrfOptions) {
+		opts.cookie = name
+	}
+}
+
+// WithCSRFTrustedOrigins allows cross origin requests from provided origins, like "https://example.com".
+func WithCSRFTrustedOrigins(origins ...string) CSRFOption {
+	return func(opts *csrfOptions) {
+		opts.trustedOrigins = append(opts.trustedOrigins, origins...)
+	}
+}
+
+// WithCSRFSignalsOptions sets options to read signals with, when token is not sent in a header.
+func WithCSRFSignalsOptions(signalsOpts ...Option) CSRFOption {
+	return func(opts *csrfOptions) {
+		opts.signalsOpts = append(opts.signalsOpts, signalsOpts...)
+	}
+}
+
+// WithCSRFErrorHandler sets a handler for requests failing csrf checks.
+// Error wraps ErrCSRF. By default requests fail with 403 Forbidden.
+func WithCSRFErrorHandler(handler func(w http.ResponseWriter, r *http.Request, err error)) CSRFOption {
+	return func(opts *csrfOptions) {
+		opts.errorHandler = handler
+	}
+}
+
+// CSRF is a standard http middleware that protects datastar actions from cross site request forgery.
+//
+// It issues a random token, stored in a cookie (double submit cookie pattern).
+// Requests with unsafe methods (POST, PUT, PATCH, DELETE) must send the same token in a header or in a signal.
+// They must also come from the same origin, checked with Sec-Fetch-Site and Origin headers.
+//
+// Token signal in a multipart form makes the middleware buffer the whole form, files included, within signals size limit.
+// Larger uploads fail with ErrSignalsTooLarge, send the token in a header or raise the limit with WithCSRFSignalsOptions.
+// Handlers can still read buffered files with StreamFiles.
+//
+// Token is put into request context, so context aware actions like ds.PostCtx send it automatically.
+// Use CSRFToken, CSRFSignals or ds.CSRFSignals to render it into the page.
+func CSRF(opts ...CSRFOption) func(next http.Handler) http.Handler {
+	options := csrfOptions{
+		header:       DefaultCSRFHeader,
+		signal:       DefaultCSRFSignal,
+		cookie:       DefaultCSRFCookie,
+		errorHandler: csrfErrorHandler,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	signalsOpts := newOptions(options.signalsOpts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, cookieErr := options.readCookie(r)
+			if cookieErr != nil {
+				token = newCSRFToken()
+				http.SetCookie(w, &http.Cookie{
+					Name:     options.cookie,
+					Value:    token,
+					Path:     "/",
+					HttpOnly: true,
+					Secure:   r.TLS != nil,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+
+			r = r.WithContext(ds.ContextWithCSRF(r.Context(), ds.CSRF{
+				Token:  token,
+				Header: options.header,
+				Signal: options.signal,
+			}))
+
+			if isSafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := options.checkOrigin(r); err != nil {
+				options.errorHandler(w, r, err)
+				return
+			}
+
+			if cookieErr != nil {
+				options.errorHandler(w, r, fmt.Errorf("%w: %w", ErrCSRF, cookieErr))
+				return
+			}
+
+			requestToken := r.Header.Get(options.header)
+			if requestToken == "" && options.signal != "" {
+				// token is not in a header, try signals without publishing them to handlers
+				var signals *RequestSignals
+				if shared := SignalsFromContext(r.Context()); shared != nil {
+					signals = shared.withOptions(options.signalsOpts)
+				} else {
+					signals = newRequestSignals(r, signalsOpts)
+					signals.preload()
+				}
+				defer signals.release()
+				err := signals.UnmarshalSignals(&requestToken, options.signal)
+				if err != nil && !errors.Is(err, ErrSignalNotFound) {
+					// like a body over the signals size limit, token may be there, but it can't be read
+					options.errorHandler(w, r, fmt.Errorf("%w: read token signal: %w", ErrCSRF, err))
+					return
+				}
+			}
+
+			if requestToken == "" {
+				options.errorHandler(w, r, fmt.Errorf("%w: token not found", ErrCSRF))
+				return
+			}
+			if subtle.ConstantTimeCompare([]byte(requestToken), []byte(token)) != 1 {
+				options.errorHandler(w, r, fmt.Errorf("%w: token mismatch", ErrCSRF))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CSRFToken returns csrf token issued by CSRF middleware, or an empty string.
+func CSRFToken(r *http.Request) string {
+	csrf, _ := ds.CSRFFromContext(r.Context())
+	return csrf.Token
+}
+
+// CSRFSignals returns signals with csrf token issued by CSRF middleware, to be sent with MergeSignals.
+// Returns nil if there is no token.
+func CSRFSignals(r *http.Request) Signals {
+	csrf, ok := ds.CSRFFromContext(r.Context())
+	if !ok || csrf.Signal == "" {
+		return nil
+	}
+	return Signals{csrf.Signal: csrf.Token}
+}
+
+func newCSRFToken() string {
+	data := make([]byte, csrfTokenSize)
+	// rand.Read never returns an error
+	_, _ = rand.Read(data)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (opts csrfOptions) readCookie(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(opts.cookie)
+	if err != nil {
+		return "", fmt.Errorf("read csrf cookie: %w", err)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(data) != csrfTokenSize {
+		return "", fmt.Errorf("invalid csrf cookie")
+	}
+	return cookie.Value, nil
+}
+
+// checkOrigin checks that request was made from the same origin or a trusted one.
+func (opts csrfOptions) checkOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin != "" && slices.Contains(opts.trustedOrigins, origin) {
+		return nil
+	}
+
+	switch site := r.Header.Get("Sec-Fetch-Site"); site {
+	case "", "same-origin", "none":
+	default:
+		return fmt.Errorf("%w: cross origin request, Sec-Fetch-Site is %s", ErrCSRF, site)
+	}
+
+	if origin == "" {
+		// older browsers may not send Origin, token check is enough then
+		return nil
+	}
+
+	originURL, err := url.Parse(origin)
+	if err != nil || originURL.Host != r.Host {
+		return fmt.Errorf("%w: cross origin request from %s", ErrCSRF, origin)
+	}
+	return nil
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func csrfErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	http.Error(w, err.Error(), http.StatusForbidden)
+}
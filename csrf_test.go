@@ -0,0 +1,192 @@
+package datastar
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestCSRF(t *testing.T) {
+	token := newCSRFToken()
+
+	handler := CSRF(WithCSRFTrustedOrigins("https://trusted.example"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CSRFToken(r)))
+	}))
+
+	runTest := func(name string, req *http.Request, expectedStatus int) {
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			is.Equal(resp.Code, expectedStatus) // status should be expected
+		})
+	}
+
+	newRequest := func(method string, body string, headers map[string]string) *http.Request {
+		req := newSignalsRequest(body)
+		req.Method = method
+		req.AddCookie(&http.Cookie{Name: DefaultCSRFCookie, Value: token})
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		return req
+	}
+
+	runTest("ok: safe method",
+		newRequest(http.MethodGet, "", nil),
+		http.StatusOK,
+	)
+	runTest("ok: header token",
+		newRequest(http.MethodPost, "", map[string]string{DefaultCSRFHeader: token}),
+		http.StatusOK,
+	)
+	runTest("ok: signal token",
+		newRequest(http.MethodPost, `{"csrf": "`+token+`"}`, nil),
+		http.StatusOK,
+	)
+	runTest("ok: same origin",
+		newRequest(http.MethodPost, "", map[string]string{
+			DefaultCSRFHeader: token,
+			"Origin":          "http://example.com",
+			"Sec-Fetch-Site":  "same-origin",
+		}),
+		http.StatusOK,
+	)
+	runTest("ok: trusted origin",
+		newRequest(http.MethodPost, "", map[string]string{
+			DefaultCSRFHeader: token,
+			"Origin":          "https://trusted.example",
+			"Sec-Fetch-Site":  "cross-site",
+		}),
+		http.StatusOK,
+	)
+	runTest("fail: missing token",
+		newRequest(http.MethodPost, `{}`, nil),
+		http.StatusForbidden,
+	)
+	runTest("fail: wrong token",
+		newRequest(http.MethodDelete, "", map[string]string{DefaultCSRFHeader: newCSRFToken()}),
+		http.StatusForbidden,
+	)
+	runTest("fail: cross site",
+		newRequest(http.MethodPost, "", map[string]string{
+			DefaultCSRFHeader: token,
+			"Sec-Fetch-Site":  "cross-site",
+		}),
+		http.StatusForbidden,
+	)
+	runTest("fail: cross origin",
+		newRequest(http.MethodPost, "", map[string]string{
+			DefaultCSRFHeader: token,
+			"Origin":          "https://evil.example",
+		}),
+		http.StatusForbidden,
+	)
+	runTest("fail: no cookie",
+		func() *http.Request {
+			req := newSignalsRequest("")
+			req.Header.Set(DefaultCSRFHeader, token)
+			return req
+		}(),
+		http.StatusForbidden,
+	)
+}
+
+func TestCSRFIssueToken(t *testing.T) {
+	is := is.New(t)
+
+	handler := CSRF()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CSRFToken(r)))
+	}))
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	cookies := resp.Result().Cookies()
+	is.Equal(len(cookies), 1)                      // token cookie should be set
+	is.Equal(cookies[0].Value, resp.Body.String()) // token in context should match cookie
+}
+
+func TestCSRFSignalsOptions(t *testing.T) {
+	is := is.New(t)
+
+	token := newCSRFToken()
+	body := `{"csrf": "` + token + `", "name": "john", "admin": true}`
+
+	var called bool
+	handler := CSRF()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		is.True(SignalsFromContext(r.Context()) == nil) // csrf should not publish signals
+
+		ds, release := New(w, r, WithAllowedSignals("csrf", "name"))
+		defer release()
+
+		var signals map[string]any
+		err := ds.UnmarshalSignals(&signals)
+		is.True(errors.Is(err, ErrSignalNotAllowed)) // handler options should apply
+	}))
+
+	req := newSignalsRequest(body)
+	req.AddCookie(&http.Cookie{Name: DefaultCSRFCookie, Value: token})
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	is.Equal(resp.Code, http.StatusOK)
+	is.True(called)
+}
+
+func TestCSRFMultipart(t *testing.T) {
+	token := newCSRFToken()
+	newRequest := func(t *testing.T, files map[string]string) *http.Request {
+		req := newMultipartRequest(t, url.Values{"csrf": {token}, "name": {"john"}}, files)
+		req.AddCookie(&http.Cookie{Name: DefaultCSRFCookie, Value: token})
+		return req
+	}
+
+	t.Run("stream files", func(t *testing.T) {
+		is := is.New(t)
+
+		var files []string
+		handler := CSRF()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ds, release := New(w, r)
+			defer release()
+
+			is.NoErr(ds.StreamFiles(func(file FormFile) error {
+				data, err := io.ReadAll(file)
+				files = append(files, file.Field+":"+string(data))
+				return err
+			})) // files buffered by csrf should be read
+
+			var name string
+			is.NoErr(ds.UnmarshalSignals(&name, "name"))
+			is.Equal(name, "john")
+		}))
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, newRequest(t, map[string]string{"avatar": "data"}))
+		is.Equal(resp.Code, http.StatusOK)
+		is.Equal(files, []string{"avatar:data"})
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		is := is.New(t)
+
+		var csrfErr error
+		handler := CSRF(WithCSRFErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			csrfErr = err
+			w.WriteHeader(http.StatusForbidden)
+		}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, newRequest(t, map[string]string{"avatar": strings.Repeat("x", 2<<20)}))
+		is.Equal(resp.Code, http.StatusForbidden)
+		is.True(errors.Is(csrfErr, ErrCSRF))
+		is.True(errors.Is(csrfErr, ErrSignalsTooLarge)) // read error should be reported, not a missing token
+	})
+}
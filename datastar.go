@@ -1,11 +1,14 @@
 package datastar
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"iter"
 	"net/http"
-	"strings"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/awryme/datastar-go/bufpool"
@@ -13,157 +16,270 @@ import (
 	"github.com/valyala/fastjson"
 )
 
-var parserPool fastjson.ParserPool
-
 // Datastar is the main engine to handle datastart requests.
 // It allows you to parse incoming signals or send events to client.
+//
+// Send and Respond methods are safe for concurrent use, writes are serialized.
+// Reading signals is not, read them before starting goroutines.
 type Datastar struct {
-	// Sets sse retry field
-	// See https://developer.mozilla.org/en-US/docs/Web/API/Server-sent_events/Using_server-sent_events#retry
-	SSERetry time.Duration
+	opts options
 
-	resp http.ResponseWriter
-	req  *http.Request
+	resp    http.ResponseWriter
+	req     *http.Request
+	signals *RequestSignals
 
-	rawData    []byte
-	jsonData   *fastjson.Value
-	jsonParser *fastjson.Parser
+	// mu serializes writes to resp
+	mu sync.Mutex
+	// rc is set when sse stream is started
+	rc *http.ResponseController
+	// streamErr is set when stream is closed because of a slow client
+	streamErr error
 
-	sse *sseserver.Server
+	eventReqOnce sync.Once
+	eventReq     *http.Request
 }
 
-// New creates a new Datastar instance.
+// New creates a new Datastar instance, configured with provided options.
+// Use Config to share options across handlers.
 // It uses fastjson.Parser to parse incoming signals.
 //
+// If request context has signals stored by SignalsMiddleware, they are used instead of reading the request.
+// Provided signal options are applied on top of the middleware options in that case:
+// size and depth limits and codec override them, filters are applied after middleware filters, so both must allow a signal.
+//
 // You should `defer release()` to reuse these parsers.
 // If you don't - nothing will leak, but parsing signals will be less optimised.
-func New(w http.ResponseWriter, r *http.Request) (ds *Datastar, release func()) {
+func New(w http.ResponseWriter, r *http.Request, opts ...Option) (ds *Datastar, release func()) {
 	ds = &Datastar{
+		opts: newOptions(opts),
 		resp: w,
 		req:  r,
 	}
 
-	release = func() {
-		if ds.jsonParser != nil {
-			parserPool.Put(ds.jsonParser)
-		}
+	if shared := SignalsFromContext(r.Context()); shared != nil {
+		// request data is owned by the middleware
+		ds.signals = shared.withOptions(opts)
+		return ds, ds.signals.release
 	}
 
-	return ds, release
+	ds.signals = newRequestSignals(r, ds.opts)
+	return ds, ds.signals.release
 }
 
 // Request signals
 
+// Signals returns request signals used by the Datastar instance.
+func (ds *Datastar) Signals() *RequestSignals {
+	return ds.signals
+}
+
 // UnmarshalSignals unmarshals a signal (or multiple) into a provided value.
-// It uses json.Unmarshal to do it, so regular Unmarshal rules apply.
-// If path is provided it will find signal value at that path.
-//
-// Path can be separated by "." or be made of individual components, like: "my.data.value" or ("my", "data", "value").
+// Refer to RequestSignals.UnmarshalSignals for details.
 func (ds *Datastar) UnmarshalSignals(value any, path ...string) error {
-	// ensure we have at least raw data
-	if err := ds.readRawData(); err != nil {
-		return err
-	}
+	return ds.signals.UnmarshalSignals(value, path...)
+}
 
-	if len(path) == 0 {
-		// fast path, just unmarshal the whole object
-		return json.Unmarshal(ds.rawData, value)
-	}
+// StreamFiles calls fn for every file uploaded with a multipart form.
+// Refer to RequestSignals.StreamFiles for details.
+func (ds *Datastar) StreamFiles(fn func(file FormFile) error) error {
+	return ds.signals.StreamFiles(fn)
+}
 
-	// slower path, use fastjson to parse
-	if err := ds.parseSignals(); err != nil {
-		return err
-	}
+// SignalString returns a string signal at path.
+// Refer to RequestSignals.SignalString for details.
+func (ds *Datastar) SignalString(path ...string) (string, error) {
+	return ds.signals.SignalString(path...)
+}
 
-	fullpath := strings.Join(path, signalSeparator)
-	keys := strings.Split(fullpath, signalSeparator)
+// SignalInt returns an integer signal at path.
+// Refer to RequestSignals.SignalInt for details.
+func (ds *Datastar) SignalInt(path ...string) (int, error) {
+	return ds.signals.SignalInt(path...)
+}
 
-	jsonValue := ds.jsonData.Get(keys...)
-	if jsonValue == nil {
-		return fmt.Errorf("signal %s not found", fullpath)
-	}
+// SignalFloat returns a number signal at path.
+// Refer to RequestSignals.SignalFloat for details.
+func (ds *Datastar) SignalFloat(path ...string) (float64, error) {
+	return ds.signals.SignalFloat(path...)
+}
 
-	return json.Unmarshal(jsonValue.MarshalTo(nil), &value)
+// SignalBool returns a boolean signal at path.
+// Refer to RequestSignals.SignalBool for details.
+func (ds *Datastar) SignalBool(path ...string) (bool, error) {
+	return ds.signals.SignalBool(path...)
 }
 
-func (ds *Datastar) parseSignals() error {
-	if ds.jsonData != nil {
-		return nil
-	}
+// SignalExists reports whether there is a signal at path.
+// Refer to RequestSignals.SignalExists for details.
+func (ds *Datastar) SignalExists(path ...string) bool {
+	return ds.signals.SignalExists(path...)
+}
 
-	if err := ds.readRawData(); err != nil {
-		return err
-	}
+// SignalKeys returns keys of an object signal at path.
+// Refer to RequestSignals.SignalKeys for details.
+func (ds *Datastar) SignalKeys(path ...string) ([]string, error) {
+	return ds.signals.SignalKeys(path...)
+}
 
-	if ds.jsonParser == nil {
-		ds.jsonParser = parserPool.Get()
-	}
+// SignalArray returns an iterator over elements of an array signal at path.
+// Refer to RequestSignals.SignalArray for details.
+func (ds *Datastar) SignalArray(path ...string) (iter.Seq2[int, *fastjson.Value], error) {
+	return ds.signals.SignalArray(path...)
+}
 
-	var err error
-	ds.jsonData, err = ds.jsonParser.ParseBytes(ds.rawData)
-	return err
+// LastEventID returns the id of the last event received by the client before reconnecting.
+// It is empty on the first connection. See WithEventIDs.
+func (ds *Datastar) LastEventID() string {
+	return ds.req.Header.Get("Last-Event-ID")
 }
 
-func (ds *Datastar) readRawData() (err error) {
-	if ds.rawData != nil {
+// SSE
+
+// ErrSlowClient is returned by Send when client doesn't read events in time, see WithWriteTimeout.
+var ErrSlowClient = fmt.Errorf("slow client: write deadline exceeded")
+
+// Send sends datastar events to client.
+// Events are created individually with respective functions or structs, or with a Patch builder.
+// Events are buffered, with reusable buffer pool.
+//
+// Multiple events are written and flushed at once, so client applies them together.
+// Nothing is sent if any event fails.
+//
+// Send is safe for concurrent use, like from a ticker and a pub/sub listener in the same handler.
+// Concurrent calls render events in parallel, but write them one at a time, so events never interleave.
+func (ds *Datastar) Send(events ...Event) error {
+	if len(events) == 0 {
 		return nil
 	}
 
-	if ds.req.Method == http.MethodGet {
-		query := ds.req.URL.Query()
+	buf := bufpool.GetBuffer()
+	defer bufpool.PutBuffer(buf)
+
+	// events are rendered without ids, they are added in write order under the lock
+	var idOffsets []int
+	var writer *sseserver.EventWriter
+	for _, event := range events {
+		if writer != nil {
+			// finish previous event
+			writer.Result()
+		}
 
-		data := query.Get("datastar")
-		if data == "" {
-			return fmt.Errorf("datastar query signals not found")
+		start := buf.Len()
+		if ds.opts.eventID != nil {
+			idOffsets = append(idOffsets, start)
+		}
+		writer = sseserver.NewEventWriter(buf, event.Name(), "", ds.opts.retry)
+		err := event.WriteEvent(writer, ds.eventRequest())
+		if err != nil {
+			return err
 		}
 
-		ds.rawData = []byte(data)
-		return nil
+		if ds.opts.debugEvents {
+			// mirror event to browser console in the same write
+			size := buf.Len() - start
+			writer.Result()
+
+			debug := ConsoleLog(ConsoleLevelDebug, "datastar event", event.Name(), size)
+			writer = sseserver.NewEventWriter(buf, debug.Name(), "", 0)
+			if err := debug.WriteEvent(writer, ds.eventRequest()); err != nil {
+				return err
+			}
+		}
 	}
+	data := writer.Result()
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
 
-	ds.rawData, err = io.ReadAll(ds.req.Body)
-	if err != nil {
-		return fmt.Errorf("read request body signals: %w", err)
+	if ds.streamErr != nil {
+		return ds.streamErr
 	}
 
-	return nil
+	if len(idOffsets) > 0 {
+		withIDs := bufpool.GetBuffer()
+		defer bufpool.PutBuffer(withIDs)
+
+		data = addEventIDs(withIDs, data, idOffsets, ds.opts.eventID)
+	}
+	return ds.writeEvent(data)
 }
 
-// SSE
+// addEventIDs writes data to dst with an id line inserted at each offset, id line is the first line of an event.
+func addEventIDs(dst *bytes.Buffer, data []byte, offsets []int, nextID func() string) []byte {
+	prev := 0
+	for _, offset := range offsets {
+		dst.Write(data[prev:offset])
+		dst.WriteString("id: ")
+		dst.WriteString(nextID())
+		dst.WriteByte('\n')
+		prev = offset
+	}
+	dst.Write(data[prev:])
+	return dst.Bytes()
+}
 
-// Send sends a datastar event to client.
-// Events are created individually with respective functions or structs.
-// Events are buffered, with reusable buffer pool.
-func (ds *Datastar) Send(event Event) error {
-	writer, release := ds.newEventWriter(event.Name())
-	defer release()
+// eventRequest returns request with Datastar options in it's context, to be used by events.
+func (ds *Datastar) eventRequest() *http.Request {
+	ds.eventReqOnce.Do(func() {
+		ctx := context.WithValue(ds.req.Context(), optionsKey{}, &ds.opts)
+		ds.eventReq = ds.req.WithContext(ctx)
+	})
+	return ds.eventReq
+}
 
-	err := event.WriteEvent(writer, ds.req)
-	if err != nil {
+// writeEvent writes and flushes event data, starting sse stream on the first call.
+// Writes have a deadline if WithWriteTimeout is set.
+func (ds *Datastar) writeEvent(data []byte) error {
+	if ds.rc == nil {
+		rc := http.NewResponseController(ds.resp)
+		if err := ds.setWriteDeadline(rc); err != nil {
+			return err
+		}
+		if _, err := sseserver.New(ds.resp, ds.req); err != nil {
+			return ds.writeError(rc, fmt.Errorf("make new sse server: %w", err))
+		}
+		ds.rc = rc
+	} else if err := ds.setWriteDeadline(ds.rc); err != nil {
 		return err
 	}
-	return ds.writeEvent(writer)
-}
 
-func (ds *Datastar) writeEvent(writer *sseserver.EventWriter) (err error) {
-	if ds.sse != nil {
-		return ds.sse.WriteEvent(writer)
+	if _, err := ds.resp.Write(data); err != nil {
+		return ds.writeError(ds.rc, fmt.Errorf("write event: %w", err))
+	}
+	if err := ds.rc.Flush(); err != nil {
+		return ds.writeError(ds.rc, fmt.Errorf("flush event: %w", err))
 	}
 
-	ds.sse, err = sseserver.New(ds.resp, ds.req)
-	if err != nil {
-		return fmt.Errorf("make new sse server: %w", err)
+	if ds.opts.writeTimeout > 0 {
+		// clear deadline, so the connection is not broken while there is nothing to send
+		if err := ds.rc.SetWriteDeadline(time.Time{}); err != nil {
+			return fmt.Errorf("clear write deadline: %w", err)
+		}
 	}
+	return nil
+}
 
-	return ds.sse.WriteEvent(writer)
+func (ds *Datastar) setWriteDeadline(rc *http.ResponseController) error {
+	if ds.opts.writeTimeout <= 0 {
+		return nil
+	}
+	if err := rc.SetWriteDeadline(time.Now().Add(ds.opts.writeTimeout)); err != nil {
+		return fmt.Errorf("set write deadline: %w", err)
+	}
+	return nil
 }
 
-func (ds *Datastar) newEventWriter(name string) (writer *sseserver.EventWriter, release func()) {
-	buf := bufpool.GetBuffer()
-	release = func() {
-		bufpool.PutBuffer(buf)
+// writeError marks deadline errors with ErrSlowClient, closing the stream if enabled.
+func (ds *Datastar) writeError(rc *http.ResponseController, err error) error {
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		return err
 	}
 
-	writer = sseserver.NewEventWriter(buf, name, "", ds.SSERetry)
-	return writer, release
+	err = fmt.Errorf("%w: %w", ErrSlowClient, err)
+	if ds.opts.closeSlowClients {
+		ds.streamErr = err
+		// fail any buffered writes, so the server drops the connection
+		_ = rc.SetWriteDeadline(time.Now())
+	}
+	return err
 }
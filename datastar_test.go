@@ -0,0 +1,141 @@
+package datastar
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestConcurrentSend(t *testing.T) {
+	is := is.New(t)
+
+	resp := httptest.NewRecorder()
+	ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil), WithEventIDs(newTestIDs()), WithDebugEvents(true))
+	defer release()
+
+	const senders, sends = 8, 50
+	var wg sync.WaitGroup
+	for sender := range senders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range sends {
+				fragment := HTML(fmt.Sprintf("<div id=\"s%d\">\n%d\n</div>", sender, idx))
+				if err := ds.Send(MergeFragments(fragment), RemoveSignals(fmt.Sprintf("s%d", sender))); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	events := strings.Split(strings.TrimSuffix(resp.Body.String(), "\n\n"), "\n\n")
+	is.Equal(len(events), senders*sends*4) // every event should be sent with a debug event
+
+	for idx := 0; idx < len(events); idx += 4 {
+		merge, remove := events[idx], events[idx+2]
+		is.True(strings.HasPrefix(merge, fmt.Sprintf("id: %d\nevent: datastar-merge-fragments\n", idx/2)))   // ids should be in order
+		is.True(strings.HasPrefix(remove, fmt.Sprintf("id: %d\nevent: datastar-remove-signals\n", idx/2+1))) // ids should be in order
+
+		var sender, value int
+		_, err := fmt.Sscanf(merge[strings.Index(merge, "data: "):],
+			"data: fragments <div id=\"s%d\">\ndata: fragments %d\ndata: fragments </div>", &sender, &value)
+		is.NoErr(err)                                                              // merge event should not be interleaved
+		is.True(strings.HasSuffix(remove, fmt.Sprintf("data: paths s%d", sender))) // events of a single send should stay together
+	}
+}
+
+func TestSendSlowRender(t *testing.T) {
+	is := is.New(t)
+
+	resp := httptest.NewRecorder()
+	ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil), WithEventIDs(newTestIDs()))
+	defer release()
+
+	rendering, unblock := make(chan struct{}), make(chan struct{})
+	slow := FragmentFunc(func(w io.Writer) error {
+		close(rendering)
+		<-unblock
+		_, err := io.WriteString(w, "slow")
+		return err
+	})
+
+	slowErr := make(chan error)
+	go func() {
+		slowErr <- ds.Send(MergeFragments(slow))
+	}()
+	<-rendering
+
+	// slow render must not block other sends
+	sent := make(chan error)
+	go func() {
+		sent <- ds.Send(MergeFragments(HTML("fast")))
+	}()
+	select {
+	case err := <-sent:
+		is.NoErr(err)
+	case <-time.After(time.Second):
+		close(unblock)
+		t.Fatal("send waits for a slow render")
+	}
+	close(unblock)
+	is.NoErr(<-slowErr)
+
+	is.Equal(resp.Body.String(), "id: 0\nevent: datastar-merge-fragments\ndata: fragments fast\n\n"+
+		"id: 1\nevent: datastar-merge-fragments\ndata: fragments slow\n\n") // ids should follow write order
+}
+
+func newTestIDs() func() string {
+	next := 0
+	return func() string {
+		id := fmt.Sprint(next)
+		next++
+		return id
+	}
+}
+
+func TestConcurrentRespond(t *testing.T) {
+	is := is.New(t)
+
+	resp := httptest.NewRecorder()
+	ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+	defer release()
+
+	rendering, unblock := make(chan struct{}), make(chan struct{})
+	slow := FragmentFunc(func(w io.Writer) error {
+		close(rendering)
+		<-unblock
+		_, err := io.WriteString(w, "<p>a</p>")
+		return err
+	})
+
+	responded := make(chan error)
+	go func() {
+		responded <- ds.RespondFragments(slow)
+	}()
+	<-rendering
+
+	// respond render must not block a concurrent send
+	sent := make(chan error)
+	go func() {
+		sent <- ds.Send(RemoveSignals("a"))
+	}()
+	select {
+	case err := <-sent:
+		is.NoErr(err)
+	case <-time.After(time.Second):
+		close(unblock)
+		t.Fatal("send waits for respond render")
+	}
+	close(unblock)
+	is.NoErr(<-responded)
+
+	is.Equal(resp.Body.String(), "event: datastar-remove-signals\ndata: paths a\n\n<p>a</p>")
+}
@@ -0,0 +1,185 @@
+package datastar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DeferredFragment is a fragment that is loaded in background, while a placeholder is shown.
+type DeferredFragment struct {
+	// Placeholder is sent immediately, like a skeleton or a spinner.
+	// Without a Selector it must have the same id as the loaded fragment, so it is replaced later.
+	// Nil placeholder is not sent.
+	Placeholder CtxFragment
+
+	// Load loads data and returns a fragment to replace the placeholder.
+	// Context is canceled when request is done or Timeout expires.
+	Load func(ctx context.Context) (CtxFragment, error)
+
+	// Fallback is sent in place of the loaded fragment if Load fails or times out.
+	// Nil fallback leaves the placeholder as is.
+	Fallback CtxFragment
+
+	// Timeout limits Load duration, zero uses Deferred default timeout.
+	Timeout time.Duration
+
+	// Selector and MergeMode are used to merge every fragment, same as in EventMergeFragments.
+	Selector  string
+	MergeMode MergeMode
+}
+
+// event returns a merge event for one of the deferred fragment states.
+func (fragment DeferredFragment) event(content CtxFragment) EventMergeFragments {
+	event := MergeCtxFragments(content)
+	event.Selector = fragment.Selector
+	event.MergeMode = fragment.MergeMode
+	return event
+}
+
+// DeferredOption configures Deferred.
+type DeferredOption func(deferred *Deferred)
+
+// WithDeferredTimeout sets default Load timeout for deferred fragments.
+// Zero value means no timeout, loads are limited only by request context. This is the default.
+func WithDeferredTimeout(timeout time.Duration) DeferredOption {
+	return func(deferred *Deferred) {
+		deferred.timeout = timeout
+	}
+}
+
+// Deferred streams fragments out of order: placeholders are sent first, then each fragment is sent as soon as it's loaded.
+//
+//	deferred := datastar.NewDeferred(ds, datastar.WithDeferredTimeout(5*time.Second))
+//	deferred.Go(skeleton("stats"), func(ctx context.Context) (datastar.CtxFragment, error) {
+//		stats, err := db.Stats(ctx)
+//		return statsView(stats), err
+//	})
+//	err := deferred.Send()
+//
+// Deferred is not safe for concurrent use, add fragments and call Send from the handler goroutine.
+type Deferred struct {
+	ds        *Datastar
+	timeout   time.Duration
+	fragments []DeferredFragment
+}
+
+// NewDeferred creates a new Deferred to send fragments with ds.
+func NewDeferred(ds *Datastar, opts ...DeferredOption) *Deferred {
+	deferred := &Deferred{
+		ds: ds,
+	}
+	for _, opt := range opts {
+		opt(deferred)
+	}
+	return deferred
+}
+
+// Add adds a deferred fragment, it's loaded when Send is called.
+func (deferred *Deferred) Add(fragment DeferredFragment) *Deferred {
+	deferred.fragments = append(deferred.fragments, fragment)
+	return deferred
+}
+
+// Go is a shortcut to add a deferred fragment with a placeholder and a load function.
+func (deferred *Deferred) Go(placeholder CtxFragment, load func(ctx context.Context) (CtxFragment, error)) *Deferred {
+	return deferred.Add(DeferredFragment{
+		Placeholder: placeholder,
+		Load:        load,
+	})
+}
+
+type deferredResult struct {
+	idx      int
+	fragment CtxFragment
+	err      error
+}
+
+// Send starts loading all fragments concurrently, sends placeholders in a single flush,
+// then sends loaded fragments in completion order. It returns when all fragments are sent.
+//
+// Load errors don't stop other fragments, fallbacks are sent for them and errors are returned joined at the end.
+// Send stops and returns the request context error if the request is done.
+func (deferred *Deferred) Send() error {
+	ctx := deferred.ds.req.Context()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// buffered, so loads finishing after Send returned don't block
+	results := make(chan deferredResult, len(deferred.fragments))
+	for idx, fragment := range deferred.fragments {
+		go func() {
+			result, err := deferred.load(ctx, fragment)
+			results <- deferredResult{idx: idx, fragment: result, err: err}
+		}()
+	}
+
+	placeholders := make([]Event, 0, len(deferred.fragments))
+	for _, fragment := range deferred.fragments {
+		if fragment.Placeholder != nil {
+			placeholders = append(placeholders, fragment.event(fragment.Placeholder))
+		}
+	}
+	if err := deferred.ds.Send(placeholders...); err != nil {
+		return fmt.Errorf("send placeholders: %w", err)
+	}
+
+	var loadErrs []error
+	for range deferred.fragments {
+		var result deferredResult
+		select {
+		case result = <-results:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		fragment := deferred.fragments[result.idx]
+		content := result.fragment
+		if result.err != nil {
+			loadErrs = append(loadErrs, fmt.Errorf("load deferred fragment %d: %w", result.idx, result.err))
+			content = fragment.Fallback
+		}
+		if content == nil {
+			continue
+		}
+
+		if err := deferred.ds.Send(fragment.event(content)); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(loadErrs...)
+}
+
+// load runs fragment Load with timeout.
+// Load is abandoned when context is done, it should respect context to not leak.
+func (deferred *Deferred) load(ctx context.Context, fragment DeferredFragment) (CtxFragment, error) {
+	timeout := fragment.Timeout
+	if timeout == 0 {
+		timeout = deferred.timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan deferredResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- deferredResult{err: fmt.Errorf("panic: %v", r)}
+			}
+		}()
+		result, err := fragment.Load(ctx)
+		done <- deferredResult{fragment: result, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.fragment, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
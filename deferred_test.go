@@ -0,0 +1,143 @@
+package datastar
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// notifyingWriter notifies about every write, so tests can wait for events to be sent.
+type notifyingWriter struct {
+	*httptest.ResponseRecorder
+	writes chan struct{}
+}
+
+func (w *notifyingWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseRecorder.Write(data)
+	w.writes <- struct{}{}
+	return n, err
+}
+
+// loadOn returns a load function that completes when ready is closed.
+func loadOn(ready chan struct{}, html string) func(ctx context.Context) (CtxFragment, error) {
+	return func(ctx context.Context) (CtxFragment, error) {
+		select {
+		case <-ready:
+			return FromFragment(HTML(html)), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestDeferred(t *testing.T) {
+	after := func(delay time.Duration, html string) func(ctx context.Context) (CtxFragment, error) {
+		return func(ctx context.Context) (CtxFragment, error) {
+			select {
+			case <-time.After(delay):
+				return FromFragment(HTML(html)), nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	t.Run("completion order", func(t *testing.T) {
+		is := is.New(t)
+
+		resp := &notifyingWriter{ResponseRecorder: httptest.NewRecorder(), writes: make(chan struct{}, 10)}
+		ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+		defer release()
+
+		slow, fast, row := make(chan struct{}), make(chan struct{}), make(chan struct{})
+		sent := make(chan error)
+		go func() {
+			sent <- NewDeferred(ds).
+				Go(FromFragment(HTML(`<div id="slow">...</div>`)), loadOn(slow, `<div id="slow">slow</div>`)).
+				Go(FromFragment(HTML(`<div id="fast">...</div>`)), loadOn(fast, `<div id="fast">fast</div>`)).
+				Add(DeferredFragment{
+					Load:      loadOn(row, "<li>row</li>"),
+					Selector:  "#rows",
+					MergeMode: ModeAppend,
+				}).
+				Send()
+		}()
+
+		// complete loads one by one, each after the previous result is written
+		<-resp.writes // placeholders
+		for _, load := range []chan struct{}{fast, row, slow} {
+			close(load)
+			<-resp.writes
+		}
+		is.NoErr(<-sent)
+
+		is.Equal(resp.Body.String(), strings.Join([]string{
+			"event: datastar-merge-fragments\ndata: fragments <div id=\"slow\">...</div>\n",
+			"event: datastar-merge-fragments\ndata: fragments <div id=\"fast\">...</div>\n",
+			"event: datastar-merge-fragments\ndata: fragments <div id=\"fast\">fast</div>\n",
+			"event: datastar-merge-fragments\ndata: selector #rows\ndata: mergeMode append\ndata: fragments <li>row</li>\n",
+			"event: datastar-merge-fragments\ndata: fragments <div id=\"slow\">slow</div>\n",
+		}, "\n")+"\n")
+	})
+
+	t.Run("timeout and errors", func(t *testing.T) {
+		is := is.New(t)
+
+		resp := httptest.NewRecorder()
+		ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+		defer release()
+
+		loadErr := errors.New("db failed")
+		err := NewDeferred(ds, WithDeferredTimeout(10*time.Millisecond)).
+			Add(DeferredFragment{
+				Placeholder: FromFragment(HTML(`<div id="a">...</div>`)),
+				Load:        after(time.Second, `<div id="a">a</div>`),
+				Fallback:    FromFragment(HTML(`<div id="a">timeout</div>`)),
+			}).
+			Add(DeferredFragment{
+				Placeholder: FromFragment(HTML(`<div id="b">...</div>`)),
+				Load: func(ctx context.Context) (CtxFragment, error) {
+					return nil, loadErr
+				},
+			}).
+			Add(DeferredFragment{
+				Load: func(ctx context.Context) (CtxFragment, error) {
+					panic("boom")
+				},
+				Fallback: FromFragment(HTML(`<div id="c">failed</div>`)),
+				Timeout:  time.Second,
+			}).
+			Send()
+		is.True(errors.Is(err, context.DeadlineExceeded)) // timeout should be returned
+		is.True(errors.Is(err, loadErr))                  // load error should be returned
+		is.True(strings.Contains(err.Error(), "boom"))    // panic should be returned
+
+		body := resp.Body.String()
+		is.True(strings.HasPrefix(body, "event: datastar-merge-fragments\ndata: fragments <div id=\"a\">...</div>\n\n"))
+		is.True(strings.Contains(body, "data: fragments <div id=\"a\">timeout</div>\n"))
+		is.True(strings.Contains(body, "data: fragments <div id=\"c\">failed</div>\n"))
+		is.Equal(strings.Count(body, "event: "), 4) // failed fragment without fallback should not be sent
+	})
+
+	t.Run("request done", func(t *testing.T) {
+		is := is.New(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		resp := httptest.NewRecorder()
+		ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+		defer release()
+
+		time.AfterFunc(10*time.Millisecond, cancel)
+		err := NewDeferred(ds).
+			Go(FromFragment(HTML(`<div id="a">...</div>`)), after(time.Second, `<div id="a">a</div>`)).
+			Send()
+		is.True(errors.Is(err, context.Canceled))
+		is.Equal(strings.Count(resp.Body.String(), "event: "), 1) // only placeholder should be sent
+	})
+}
@@ -0,0 +1,82 @@
+package ds
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// CSRF contains a csrf token and names used to send it to the server.
+// It is put into context by a csrf middleware, like datastar.CSRF.
+type CSRF struct {
+	// Token is the csrf token value.
+	Token string
+
+	// Header is the name of the header to send the token with.
+	Header string
+
+	// Signal is the name of the signal to send the token with.
+	Signal string
+}
+
+type csrfKey struct{}
+
+// ContextWithCSRF returns a context with csrf token, used by context aware actions.
+func ContextWithCSRF(ctx context.Context, csrf CSRF) context.Context {
+	return context.WithValue(ctx, csrfKey{}, csrf)
+}
+
+// CSRFFromContext returns csrf token from context.
+func CSRFFromContext(ctx context.Context) (CSRF, bool) {
+	csrf, ok := ctx.Value(csrfKey{}).(CSRF)
+	return csrf, ok
+}
+
+// CSRFSignals returns a signals object with csrf token to be used in `data-signals` attribute.
+// Returns an empty object if there is no token in context.
+func CSRFSignals(ctx context.Context) string {
+	csrf, ok := CSRFFromContext(ctx)
+	if !ok || csrf.Signal == "" {
+		return "{}"
+	}
+	return mapToJs(map[string]string{
+		csrf.Signal: fmt.Sprintf("'%s'", csrf.Token),
+	})
+}
+
+// Works the same as Post, but sends csrf token from context in a header.
+func PostCtx(ctx context.Context, url string, opts ...Options) string {
+	return Post(url, withCSRF(ctx, opts)...)
+}
+
+// Works the same as Put, but sends csrf token from context in a header.
+func PutCtx(ctx context.Context, url string, opts ...Options) string {
+	return Put(url, withCSRF(ctx, opts)...)
+}
+
+// Works the same as Patch, but sends csrf token from context in a header.
+func PatchCtx(ctx context.Context, url string, opts ...Options) string {
+	return Patch(url, withCSRF(ctx, opts)...)
+}
+
+// Works the same as Delete, but sends csrf token from context in a header.
+func DeleteCtx(ctx context.Context, url string, opts ...Options) string {
+	return Delete(url, withCSRF(ctx, opts)...)
+}
+
+func withCSRF(ctx context.Context, opts []Options) []Options {
+	csrf, ok := CSRFFromContext(ctx)
+	if !ok || csrf.Header == "" {
+		return opts
+	}
+
+	headers := make(map[string]string)
+	for _, opt := range opts {
+		maps.Copy(headers, opt.Headers)
+	}
+	headers[csrf.Header] = csrf.Token
+
+	// opts is the caller's variadic slice, appending must not write into it
+	return slices.Concat(opts, []Options{{Headers: headers}})
+}
@@ -0,0 +1,33 @@
+package ds
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestCSRFActions(t *testing.T) {
+	is := is.New(t)
+
+	ctx := ContextWithCSRF(context.Background(), CSRF{
+		Token:  "tok",
+		Header: "X-CSRF-Token",
+		Signal: "csrf",
+	})
+
+	is.Equal(PostCtx(ctx, "/save"), "@post('/save', {headers: {'X-CSRF-Token': 'tok'}})")
+	is.Equal(
+		DeleteCtx(ctx, "/item", Options{Headers: map[string]string{"X-Other": "1"}}),
+		"@delete('/item', {headers: {'X-CSRF-Token': 'tok', 'X-Other': '1'}})",
+	)
+	is.Equal(PostCtx(context.Background(), "/save"), "@post(`/save`)")
+
+	opts := make([]Options, 1, 2)
+	opts[0] = Options{Headers: map[string]string{"X-Other": "1"}}
+	PostCtx(ctx, "/save", opts...)
+	is.Equal(opts[:2][1].Headers, nil) // caller's variadic slice should not be changed
+
+	is.Equal(CSRFSignals(ctx), "{csrf: 'tok'}")
+	is.Equal(CSRFSignals(context.Background()), "{}")
+}
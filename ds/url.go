@@ -75,7 +75,10 @@ func mergeOpts(opts []Options) *Options {
 		// fast path
 		return nil
 	}
-	mergedOpts := &Options{}
+	mergedOpts := &Options{
+		Query:   make(map[string]any),
+		Headers: make(map[string]string),
+	}
 	for _, opt := range opts {
 		if len(opt.Query) > 0 {
 			maps.Copy(mergedOpts.Query, opt.Query)
@@ -129,7 +132,8 @@ func actionURL(method string, url string, options *Options) string {
 	if len(options.Headers) > 0 {
 		dsHeaders := make(map[string]string)
 		for k, v := range options.Headers {
-			dsHeaders[k] = fmt.Sprintf("'%s'", v)
+			// header names are quoted, because they usually contain dashes
+			dsHeaders[fmt.Sprintf("'%s'", k)] = fmt.Sprintf("'%s'", v)
 		}
 		dsOpts["headers"] = mapToJs(dsHeaders)
 	}
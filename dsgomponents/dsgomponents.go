@@ -0,0 +1,26 @@
+// Package dsgomponents adapts gomponents nodes to datastar fragments.
+//
+// Nodes already implement datastar.Fragment, so they can be passed to datastar.MergeFragments directly.
+// This package adds shortcuts to use them with slices of nodes.
+package dsgomponents
+
+import (
+	"github.com/awryme/datastar-go"
+	"maragu.dev/gomponents"
+)
+
+var _ datastar.Fragment = gomponents.Node(nil)
+
+// Merge is a shortcut to create a `datastar-merge-fragments` event with gomponents nodes.
+func Merge(nodes ...gomponents.Node) datastar.EventMergeFragments {
+	return datastar.MergeFragments(Fragments(nodes...)...)
+}
+
+// Fragments converts gomponents nodes to datastar fragments.
+func Fragments(nodes ...gomponents.Node) []datastar.Fragment {
+	fragments := make([]datastar.Fragment, 0, len(nodes))
+	for _, node := range nodes {
+		fragments = append(fragments, node)
+	}
+	return fragments
+}
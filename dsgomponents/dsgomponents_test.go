@@ -0,0 +1,25 @@
+package dsgomponents
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/awryme/datastar-go"
+	"github.com/matryer/is"
+	g "maragu.dev/gomponents"
+	h "maragu.dev/gomponents/html"
+)
+
+func TestMerge(t *testing.T) {
+	is := is.New(t)
+
+	resp := httptest.NewRecorder()
+	ds, release := datastar.New(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+	defer release()
+
+	is.NoErr(ds.Send(Merge(h.Div(h.ID("a"), g.Text("<a>")), h.Div(h.ID("b")))))
+	is.Equal(resp.Body.String(), "event: datastar-merge-fragments\n"+
+		"data: fragments <div id=\"a\">&lt;a&gt;</div>\n"+
+		"data: fragments <div id=\"b\"></div>\n\n")
+}
@@ -0,0 +1,26 @@
+// Package dstempl adapts templ components to datastar fragments.
+//
+// Components already implement datastar.CtxFragment, so they can be passed to datastar.MergeCtxFragments directly.
+// This package adds shortcuts to use them with slices of components.
+package dstempl
+
+import (
+	"github.com/a-h/templ"
+	"github.com/awryme/datastar-go"
+)
+
+var _ datastar.CtxFragment = templ.Component(nil)
+
+// Merge is a shortcut to create a `datastar-merge-fragments` event with templ components.
+func Merge(components ...templ.Component) datastar.EventMergeFragments {
+	return datastar.MergeCtxFragments(Fragments(components...)...)
+}
+
+// Fragments converts templ components to datastar ctx fragments.
+func Fragments(components ...templ.Component) []datastar.CtxFragment {
+	fragments := make([]datastar.CtxFragment, 0, len(components))
+	for _, component := range components {
+		fragments = append(fragments, component)
+	}
+	return fragments
+}
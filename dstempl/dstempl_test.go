@@ -0,0 +1,24 @@
+package dstempl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/a-h/templ"
+	"github.com/awryme/datastar-go"
+	"github.com/matryer/is"
+)
+
+func TestMerge(t *testing.T) {
+	is := is.New(t)
+
+	resp := httptest.NewRecorder()
+	ds, release := datastar.New(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+	defer release()
+
+	is.NoErr(ds.Send(Merge(templ.Raw(`<div id="a">a</div>`), templ.Raw(`<div id="b">b</div>`))))
+	is.Equal(resp.Body.String(), "event: datastar-merge-fragments\n"+
+		"data: fragments <div id=\"a\">a</div>\n"+
+		"data: fragments <div id=\"b\">b</div>\n\n")
+}
@@ -0,0 +1,17 @@
+module github.com/awryme/datastar-go/dstempl
+
+go 1.24.1
+
+require (
+	github.com/a-h/templ v0.3.977
+	github.com/awryme/datastar-go v0.0.0
+	github.com/matryer/is v1.4.1
+)
+
+require (
+	github.com/awryme/sse-go v0.0.0-20250420213924-fedd2fae5f1e // indirect
+	github.com/valyala/fastjson v1.6.4 // indirect
+	golang.org/x/net v0.50.0 // indirect
+)
+
+replace github.com/awryme/datastar-go => ../
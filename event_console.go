@@ -0,0 +1,48 @@
+package datastar
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConsoleLevel is a browser console method to log with.
+type ConsoleLevel string
+
+const (
+	ConsoleLevelLog   ConsoleLevel = "log"   //Logs with console.log.
+	ConsoleLevelDebug ConsoleLevel = "debug" //Logs with console.debug.
+	ConsoleLevelInfo  ConsoleLevel = "info"  //Logs with console.info.
+	ConsoleLevelWarn  ConsoleLevel = "warn"  //Logs with console.warn.
+	ConsoleLevelError ConsoleLevel = "error" //Logs with console.error.
+)
+
+// ConsoleLog is a shortcut to create a `datastar-execute-script` event that logs args to the browser console.
+//
+// Args are encoded with encoding/json, so structs and maps show up as objects in devtools.
+// Errors are logged as their message strings.
+// Encoding errors are returned when the event is sent.
+func ConsoleLog(level ConsoleLevel, args ...any) EventExecuteScript {
+	event := EventExecuteScript{
+		AutoRemove: true,
+	}
+
+	switch level {
+	case ConsoleLevelLog, ConsoleLevelDebug, ConsoleLevelInfo, ConsoleLevelWarn, ConsoleLevelError:
+	default:
+		event.err = fmt.Errorf("unknown console level %q", level)
+		return event
+	}
+
+	values := make([]string, 0, len(args))
+	for idx, arg := range args {
+		value, err := jsValue(arg)
+		if err != nil {
+			event.err = fmt.Errorf("encode console arg %d: %w", idx, err)
+			return event
+		}
+		values = append(values, value)
+	}
+
+	event.Script = []string{fmt.Sprintf("console.%s(%s)", level, strings.Join(values, ", "))}
+	return event
+}
@@ -0,0 +1,46 @@
+package datastar
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestConsoleLog(t *testing.T) {
+	is := is.New(t)
+
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	event := ConsoleLog(ConsoleLevelWarn, "user", user{Name: "</script>"}, 42, errors.New("failed"))
+	is.NoErr(event.err)
+	is.Equal(event.Script, []string{`console.warn("user", {"name":"\u003c/script\u003e"}, 42, "failed")`})
+
+	event = ConsoleLog(ConsoleLevel("alert"), "x")
+	is.True(event.err != nil) // unknown level should fail
+
+	event = ConsoleLog(ConsoleLevelLog, make(chan int))
+	is.True(event.err != nil) // unsupported value should fail
+}
+
+func TestDebugEvents(t *testing.T) {
+	is := is.New(t)
+
+	resp := httptest.NewRecorder()
+	ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil), WithDebugEvents(true))
+	defer release()
+
+	is.NoErr(ds.Send(RemoveSignals("a")))
+
+	event := "event: datastar-remove-signals\ndata: paths a\n"
+	debug := "event: datastar-execute-script\ndata: autoRemove true\n" +
+		`data: script console.debug("datastar event", "datastar-remove-signals", 45)` + "\n"
+	is.Equal(len(event), 45)
+	is.Equal(resp.Body.String(), event+"\n"+debug+"\n")
+	is.Equal(strings.Count(resp.Body.String(), "event: "), 2) // only one debug event should be sent
+}
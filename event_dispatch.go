@@ -0,0 +1,66 @@
+package datastar
+
+import "fmt"
+
+// DispatchOptions set CustomEvent options for DispatchEvent.
+//
+// Refer to CustomEvent docs for details: https://developer.mozilla.org/en-US/docs/Web/API/CustomEvent/CustomEvent
+type DispatchOptions struct {
+	// Bubbles determines whether the event bubbles up through the DOM.
+	Bubbles bool
+
+	// Cancelable determines whether the event can be canceled.
+	Cancelable bool
+
+	// Composed determines whether the event propagates across the shadow DOM boundary into the standard DOM.
+	Composed bool
+}
+
+// DispatchEvent is a shortcut to create a `datastar-execute-script` event that dispatches a CustomEvent on the client.
+// Use it to talk to web components and other scripts listening to custom events.
+//
+// Event is dispatched on every element matching selector, or on window if selector is empty.
+// Detail is encoded with encoding/json and set as event detail, encoding errors are returned when the event is sent.
+func DispatchEvent(selector string, eventName string, detail any, opts ...DispatchOptions) EventExecuteScript {
+	event := EventExecuteScript{
+		AutoRemove: true,
+	}
+
+	if eventName == "" {
+		event.err = fmt.Errorf("dispatch event: event name is empty")
+		return event
+	}
+
+	init := struct {
+		Detail     any  `json:"detail"`
+		Bubbles    bool `json:"bubbles"`
+		Cancelable bool `json:"cancelable"`
+		Composed   bool `json:"composed"`
+	}{
+		Detail: detail,
+	}
+	for _, opt := range opts {
+		init.Bubbles = init.Bubbles || opt.Bubbles
+		init.Cancelable = init.Cancelable || opt.Cancelable
+		init.Composed = init.Composed || opt.Composed
+	}
+
+	initValue, err := jsValue(init)
+	if err != nil {
+		event.err = fmt.Errorf("dispatch event %s: encode detail: %w", eventName, err)
+		return event
+	}
+
+	customEvent := fmt.Sprintf("new CustomEvent(%s, %s)", jsString(eventName), initValue)
+	if selector == "" {
+		event.Script = []string{fmt.Sprintf("window.dispatchEvent(%s)", customEvent)}
+		return event
+	}
+
+	event.Script = []string{fmt.Sprintf(
+		"document.querySelectorAll(%s).forEach((el) => el.dispatchEvent(%s))",
+		jsString(selector),
+		customEvent,
+	)}
+	return event
+}
@@ -0,0 +1,34 @@
+package datastar
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestDispatchEvent(t *testing.T) {
+	is := is.New(t)
+
+	event := DispatchEvent("", "cart-updated", map[string]int{"count": 3})
+	is.NoErr(event.err)
+	is.Equal(event.Script, []string{
+		`window.dispatchEvent(new CustomEvent("cart-updated", {"detail":{"count":3},"bubbles":false,"cancelable":false,"composed":false}))`,
+	})
+
+	event = DispatchEvent(`my-cart[data-id="1"]`, "refresh", nil, DispatchOptions{Bubbles: true, Composed: true})
+	is.NoErr(event.err)
+	is.Equal(event.Script, []string{
+		`document.querySelectorAll("my-cart[data-id=\"1\"]").forEach((el) => el.dispatchEvent(new CustomEvent("refresh", {"detail":null,"bubbles":true,"cancelable":false,"composed":true})))`,
+	})
+
+	event = DispatchEvent("", "x", "</script>")
+	is.Equal(event.Script, []string{
+		`window.dispatchEvent(new CustomEvent("x", {"detail":"\u003c/script\u003e","bubbles":false,"cancelable":false,"composed":false}))`,
+	})
+
+	event = DispatchEvent("", "", nil)
+	is.True(event.err != nil) // empty event name should fail
+
+	event = DispatchEvent("", "x", func() {})
+	is.True(event.err != nil) // unsupported detail should fail
+}
@@ -1,7 +1,9 @@
 package datastar
 
 import (
+	"maps"
 	"net/http"
+	"slices"
 
 	"github.com/awryme/sse-go/sseserver"
 )
@@ -24,7 +26,12 @@ type EventExecuteScript struct {
 	AutoRemove bool
 
 	// Attributes to add to the script element.
+	// Attributes are validated and sent in sorted order.
+	// CSP nonce attribute is added automatically, see ContextWithNonce.
 	Attributes map[string]string
+
+	// err is set by constructors when script can't be built, it's returned when the event is written.
+	err error
 }
 
 func (event EventExecuteScript) Name() string {
@@ -32,14 +39,20 @@ func (event EventExecuteScript) Name() string {
 }
 
 func (event EventExecuteScript) WriteEvent(writer *sseserver.EventWriter, req *http.Request) error {
+	if event.err != nil {
+		return event.err
+	}
+
 	if event.AutoRemove {
 		writer.Write("autoRemove true")
 	}
 
-	if len(event.Attributes) > 0 {
-		for name, value := range event.Attributes {
-			writer.Format("attributes %s %s", name, value)
-		}
+	attributes, err := event.scriptAttributes(req)
+	if err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(attributes)) {
+		writer.Format("attributes %s %s", name, attributes[name])
 	}
 
 	for _, script := range event.Script {
@@ -49,3 +62,24 @@ func (event EventExecuteScript) WriteEvent(writer *sseserver.EventWriter, req *h
 	}
 	return nil
 }
+
+// scriptAttributes returns validated script attributes, with CSP nonce added.
+func (event EventExecuteScript) scriptAttributes(req *http.Request) (map[string]string, error) {
+	attributes := event.Attributes
+
+	nonce := optionsFromRequest(req).nonce(req)
+	if _, ok := attributes["nonce"]; !ok && nonce != "" {
+		attributes = maps.Clone(attributes)
+		if attributes == nil {
+			attributes = make(map[string]string, 1)
+		}
+		attributes["nonce"] = nonce
+	}
+
+	for name, value := range attributes {
+		if err := validateAttribute(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return attributes, nil
+}
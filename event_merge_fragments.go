@@ -1,10 +1,11 @@
 package datastar
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"net/http"
+	"sync"
 
 	"github.com/awryme/datastar-go/bufpool"
 	"github.com/awryme/sse-go/sseserver"
@@ -12,26 +13,26 @@ import (
 
 // MergeFragments is a shortcut to create a `datastar-merge-fragments` event with a set of fragments.
 func MergeFragments(fragments ...Fragment) EventMergeFragments {
-	return EventMergeFragments{
-		Fragments: fragments,
-	}
+	return MergeCtxFragments(FromFragments(fragments...)...)
 }
 
 // MergeCtxFragments is a shortcut to create a `datastar-merge-fragments` event with a set of ctx aware fragments.
+// Use FromFragment to mix in other fragments, they are rendered in order.
 func MergeCtxFragments(fragments ...CtxFragment) EventMergeFragments {
 	return EventMergeFragments{
-		CtxFragments: fragments,
+		Fragments: fragments,
 	}
 }
 
 // EventMergeFragments is the implementation for `datastar-merge-fragments` event.
-// You can set both Fragment and CtxFragments, as many as you need in a single request.
+// Fragments can be mixed, as many as you need in a single request, they are rendered in order.
 // Event options can be set with respective fields.
 type EventMergeFragments struct {
-	Fragments    []Fragment
-	CtxFragments []CtxFragment
+	// Fragments to merge, use FromFragment to add a Fragment.
+	Fragments []CtxFragment
 
 	// Selects the target element of the merge process using a CSS selector.
+	// Use ByID, ByClass and Attr to build selectors safely.
 	Selector string
 
 	// Sets the mode to merge fragments with.
@@ -48,6 +49,9 @@ func (event EventMergeFragments) Name() string {
 
 func (event EventMergeFragments) WriteEvent(writer *sseserver.EventWriter, req *http.Request) error {
 	if event.Selector != "" {
+		if err := ValidateSelector(event.Selector); err != nil {
+			return err
+		}
 		writer.Format("selector %s", event.Selector)
 	}
 	if event.MergeMode != "" {
@@ -57,39 +61,209 @@ func (event EventMergeFragments) WriteEvent(writer *sseserver.EventWriter, req *
 		writer.Write("useViewTransition true")
 	}
 
-	ctx := req.Context()
+	return event.renderFragments(req, encodeSSE, func(data []byte) error {
+		// data is already split into `fragments` lines
+		writer.Write(string(data))
+		return nil
+	})
+}
+
+// fragmentEncoding sets the format of rendered fragments passed to renderFragments callback.
+type fragmentEncoding int
 
-	buf := bufpool.GetBuffer()
-	defer bufpool.PutBuffer(buf)
+const (
+	encodeHTML fragmentEncoding = iota // fragments are passed as is
+	encodeSSE                          // fragments are split into `fragments` data lines, see encodeFragmentLines
+)
 
-	renderFragment := func(render func(context.Context, io.Writer) error) error {
-		buf.Reset()
+// renderFragments renders fragments in order, passing each rendered fragment to fn.
+// Fragments are minified and validated if enabled in request options.
+// Data passed to fn is valid only during the call.
+func (event EventMergeFragments) renderFragments(req *http.Request, encoding fragmentEncoding, fn func(data []byte) error) error {
+	opts := optionsFromRequest(req)
+	validator := newFragmentValidator(opts.fragmentValidation, event.Selector)
+
+	scratch := bufpool.GetBuffer()
+	defer bufpool.PutBuffer(scratch)
 
-		if err := render(ctx, buf); err != nil {
-			return fmt.Errorf("render fragment: %w", err)
+	output := func(result renderedFragment) error {
+		data := result.data
+		if result.encoded {
+			if validator != nil || encoding == encodeHTML {
+				scratch.Reset()
+				decodeFragmentLines(scratch, data)
+				if err := validator.validate(req.Context(), scratch.String()); err != nil {
+					return err
+				}
+				if encoding == encodeHTML {
+					data = scratch.Bytes()
+				}
+			}
+			return fn(data)
 		}
 
-		for _, line := range splitLines(buf.String()) {
-			writer.Format("fragments %s", line)
+		if err := validator.validate(req.Context(), string(data)); err != nil {
+			return err
 		}
-		return nil
+		if encoding == encodeSSE {
+			scratch.Reset()
+			encodeFragmentLines(scratch, data)
+			data = scratch.Bytes()
+		}
+		return fn(data)
 	}
 
-	for _, fragment := range event.CtxFragments {
-		err := renderFragment(fragment.Render)
+	if opts.parallelRender > 1 && len(event.Fragments) > 1 {
+		results, release, err := event.renderParallel(req.Context(), opts)
+		defer release()
 		if err != nil {
 			return err
 		}
+
+		for _, result := range results {
+			if err := output(result); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
+	buf := bufpool.GetBuffer()
+	defer bufpool.PutBuffer(buf)
+
 	for _, fragment := range event.Fragments {
-		err := renderFragment(func(ctx context.Context, w io.Writer) error {
-			return fragment.Render(w)
-		})
+		buf.Reset()
+		result, err := renderOne(req.Context(), opts, buf, fragment)
 		if err != nil {
 			return err
 		}
+		if err := output(result); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// renderParallel renders fragments concurrently into separate buffers, at most opts.parallelRender at a time.
+// First error cancels the context of other renders.
+// Release must be called after results are used, even on error.
+func (event EventMergeFragments) renderParallel(ctx context.Context, opts *options) (results []renderedFragment, release func(), err error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	results = make([]renderedFragment, len(event.Fragments))
+	buffers := make([]*bytes.Buffer, len(event.Fragments))
+	release = func() {
+		for _, buf := range buffers {
+			if buf != nil {
+				bufpool.PutBuffer(buf)
+			}
+		}
+	}
+
+	limit := make(chan struct{}, opts.parallelRender)
+	var wg sync.WaitGroup
+loop:
+	for idx, fragment := range event.Fragments {
+		select {
+		case limit <- struct{}{}:
+		case <-ctx.Done():
+			break loop
+		}
+
+		buffers[idx] = bufpool.GetBuffer()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-limit }()
+			defer func() {
+				if r := recover(); r != nil {
+					cancel(fmt.Errorf("render fragment: panic: %v", r))
+				}
+			}()
+
+			result, err := renderOne(ctx, opts, buffers[idx], fragment)
+			if err != nil {
+				cancel(err)
+				return
+			}
+			results[idx] = result
+		}()
+	}
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil {
+		return nil, release, err
+	}
+	return results, release, nil
+}
+
+// renderedFragment is a fragment rendered as html, or loaded from cache as sse data lines.
+type renderedFragment struct {
+	data    []byte
+	encoded bool
+}
+
+// renderOne renders a fragment into buf, cached fragments are loaded from cache instead.
+func renderOne(ctx context.Context, opts *options, buf *bytes.Buffer, fragment CtxFragment) (renderedFragment, error) {
+	if cached, ok := fragment.(cachedFragment); ok {
+		data, err := cached.load(ctx, opts)
+		return renderedFragment{data: data, encoded: true}, err
+	}
+
+	err := renderHTML(ctx, opts, buf, fragment)
+	return renderedFragment{data: buf.Bytes()}, err
+}
+
+// renderHTML renders a fragment into buf, minifying it if enabled in options.
+func renderHTML(ctx context.Context, opts *options, buf *bytes.Buffer, fragment CtxFragment) error {
+	if !opts.minifyFragments {
+		return renderFragment(ctx, buf, fragment)
+	}
+
+	rendered := bufpool.GetBuffer()
+	defer bufpool.PutBuffer(rendered)
+
+	if err := renderFragment(ctx, rendered, fragment); err != nil {
+		return err
+	}
+	minifyHTML(buf, rendered.Bytes())
+	return nil
+}
+
+// encodeFragmentLines writes html as `fragments` data lines, to be written with a single EventWriter.Write call.
+// First line has no "data: " prefix and last line has no newline, EventWriter.Write adds them.
+func encodeFragmentLines(dst *bytes.Buffer, html []byte) {
+	dst.WriteString(fragmentsLinePrefix)
+	for {
+		idx := bytes.IndexByte(html, '\n')
+		if idx < 0 {
+			dst.Write(html)
+			return
+		}
+		dst.Write(html[:idx])
+		dst.WriteString(fragmentsLineSeparator)
+		html = html[idx+1:]
+	}
+}
+
+// decodeFragmentLines writes html encoded with encodeFragmentLines to dst.
+func decodeFragmentLines(dst *bytes.Buffer, data []byte) {
+	data = bytes.TrimPrefix(data, []byte(fragmentsLinePrefix))
+	for {
+		idx := bytes.Index(data, []byte(fragmentsLineSeparator))
+		if idx < 0 {
+			dst.Write(data)
+			return
+		}
+		dst.Write(data[:idx])
+		dst.WriteByte('\n')
+		data = data[idx+len(fragmentsLineSeparator):]
+	}
+}
+
+const (
+	fragmentsLinePrefix    = "fragments "
+	fragmentsLineSeparator = "\ndata: " + fragmentsLinePrefix
+)
@@ -1,7 +1,6 @@
 package datastar
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -36,7 +35,7 @@ type EventMergeSignals struct {
 	// Signals values, refer to MergeSignals for docs.
 	Signals Signals
 
-	// Value is marshalled to a json object and sent to frontend.
+	// Value is marshalled to a json object with JSONCodec and sent to frontend.
 	Value any
 
 	// OnlyIfMissing determines whether to update the signals with new values only if the key does not exist.
@@ -48,24 +47,33 @@ func (event EventMergeSignals) Name() string {
 }
 
 func (event EventMergeSignals) WriteEvent(writer *sseserver.EventWriter, req *http.Request) error {
+	data, err := event.marshal(req)
+	if err != nil {
+		return err
+	}
+
+	if event.OnlyIfMissing {
+		writer.Write("onlyIfMissing true")
+	}
+
+	writer.Format("signals %s", string(data))
+	return nil
+}
+
+// marshal transforms signals and marshals resulting value.
+func (event EventMergeSignals) marshal(req *http.Request) ([]byte, error) {
 	// transform signals
 	if len(event.Signals) > 0 {
 		err := transformTopLevelSignals(event.Signals)
 		if err != nil {
-			return fmt.Errorf("transform signals: %w", err)
+			return nil, fmt.Errorf("transform signals: %w", err)
 		}
 		event.Value = event.Signals
 	}
 
-	if event.OnlyIfMissing {
-		writer.Write("onlyIfMissing true")
-	}
-
-	data, err := json.Marshal(event.Value)
+	data, err := optionsFromRequest(req).codec.Marshal(event.Value)
 	if err != nil {
-		return fmt.Errorf("marshal signals: %w", err)
+		return nil, fmt.Errorf("marshal signals: %w", err)
 	}
-
-	writer.Format("signals %s", string(data))
-	return nil
+	return data, nil
 }
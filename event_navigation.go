@@ -0,0 +1,56 @@
+package datastar
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Redirect is a shortcut to create a `datastar-execute-script` event that navigates the browser to url.
+//
+// Url is escaped and can be any string, like user input.
+// Urls with script schemes (javascript:, vbscript:, data:) are rejected with an error when event is sent.
+func Redirect(url string) EventExecuteScript {
+	return RedirectAfter(url, 0)
+}
+
+// RedirectAfter works the same as Redirect, but navigates after a delay.
+func RedirectAfter(url string, delay time.Duration) EventExecuteScript {
+	script := fmt.Sprintf("setTimeout(() => window.location.href = %s, %d)", jsString(url), delay.Milliseconds())
+	return navigationScript(url, script)
+}
+
+// PushURL is a shortcut to create a `datastar-execute-script` event that adds url to the browser history, without navigation.
+// It uses history.pushState.
+func PushURL(url string) EventExecuteScript {
+	script := fmt.Sprintf(`window.history.pushState({}, "", %s)`, jsString(url))
+	return navigationScript(url, script)
+}
+
+// ReplaceURL is a shortcut to create a `datastar-execute-script` event that replaces current url in the browser history, without navigation.
+// It uses history.replaceState.
+func ReplaceURL(url string) EventExecuteScript {
+	script := fmt.Sprintf(`window.history.replaceState({}, "", %s)`, jsString(url))
+	return navigationScript(url, script)
+}
+
+func navigationScript(rawURL string, script string) EventExecuteScript {
+	return EventExecuteScript{
+		Script:     []string{script},
+		AutoRemove: true,
+		err:        checkNavigationURL(rawURL),
+	}
+}
+
+func checkNavigationURL(rawURL string) error {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return fmt.Errorf("invalid navigation url: %w", err)
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "javascript", "vbscript", "data":
+		return fmt.Errorf("navigation url with %s scheme is not allowed", parsed.Scheme)
+	}
+	return nil
+}
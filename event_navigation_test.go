@@ -0,0 +1,89 @@
+package datastar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestNavigation(t *testing.T) {
+	runTest := func(name string, event EventExecuteScript, expectedScript string, expectErr bool) {
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+
+			resp := httptest.NewRecorder()
+			ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+			defer release()
+
+			err := ds.Send(event)
+			if expectErr {
+				is.True(err != nil) // unsafe url should fail
+				return
+			}
+			is.NoErr(err)
+
+			expected := "event: datastar-execute-script\ndata: autoRemove true\ndata: script " + expectedScript + "\n\n"
+			is.Equal(resp.Body.String(), expected)
+		})
+	}
+
+	runTest("redirect",
+		Redirect("/login?next=/home"),
+		`setTimeout(() => window.location.href = "/login?next=/home", 0)`,
+		false,
+	)
+	runTest("redirect after",
+		RedirectAfter("https://example.com", 1500*time.Millisecond),
+		`setTimeout(() => window.location.href = "https://example.com", 1500)`,
+		false,
+	)
+	runTest("push url",
+		PushURL("/items?page=2"),
+		`window.history.pushState({}, "", "/items?page=2")`,
+		false,
+	)
+	runTest("replace url",
+		ReplaceURL("/items"),
+		`window.history.replaceState({}, "", "/items")`,
+		false,
+	)
+	runTest("escape quotes and tags",
+		PushURL(`/a"b'c</script><script>alert(1)</script>`),
+		`window.history.pushState({}, "", "/a\"b'c\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e")`,
+		false,
+	)
+	runTest("escape line separators",
+		ReplaceURL("/a\u2028b"),
+		`window.history.replaceState({}, "", "/a\u2028b")`,
+		false,
+	)
+	runTest("fail: javascript scheme",
+		Redirect(" JavaScript:alert(1)"),
+		"",
+		true,
+	)
+	runTest("fail: data scheme",
+		Redirect("data:text/html,<script>alert(1)</script>"),
+		"",
+		true,
+	)
+	runTest("fail: control characters",
+		Redirect("/a\nb"),
+		"",
+		true,
+	)
+}
+
+func TestNavigationScriptIsSingleLine(t *testing.T) {
+	is := is.New(t)
+
+	for _, url := range []string{"/a\u2028b", "/a\u2029b", "/\"'`${x}`"} {
+		event := PushURL(url)
+		is.Equal(len(event.Script), 1)
+		is.True(!strings.ContainsAny(event.Script[0], "\n\r\u2028\u2029")) // script should be a single safe line
+	}
+}
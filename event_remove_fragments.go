@@ -1,21 +1,33 @@
 package datastar
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/awryme/sse-go/sseserver"
 )
 
 // RemoveFragments is a shortcut to create a `datastar-remove-fragments` event.
-func RemoveFragments(selector string) EventRemoveFragments {
-	return EventRemoveFragments{selector}
+// Elements matching any of selectors are removed.
+func RemoveFragments(selectors ...string) EventRemoveFragments {
+	return EventRemoveFragments{
+		Selectors: selectors,
+	}
 }
 
 // EventRemoveFragments is the implementation for `datastar-remove-fragments` event.
 type EventRemoveFragments struct {
 	// Selector is used to match the elements to remove from the DOM.
-	// Only one selector can be provided in the event.
+	// Use ByID, ByClass and Attr to build selectors safely.
 	Selector string
+
+	// Selectors are additional selectors to match the elements to remove.
+	// They are combined with Selector into a single selector list, so one event removes all matching elements.
+	Selectors []string
+
+	// Determines whether to use view transitions when removing from the DOM.
+	UseViewTransition bool
 }
 
 func (event EventRemoveFragments) Name() string {
@@ -23,6 +35,22 @@ func (event EventRemoveFragments) Name() string {
 }
 
 func (event EventRemoveFragments) WriteEvent(writer *sseserver.EventWriter, req *http.Request) error {
-	writer.Format("selector %s", event.Selector)
+	selectors := event.Selectors
+	if event.Selector != "" {
+		selectors = append([]string{event.Selector}, selectors...)
+	}
+	if len(selectors) == 0 {
+		return fmt.Errorf("%w: no selectors to remove fragments", ErrInvalidSelector)
+	}
+	for _, selector := range selectors {
+		if err := ValidateSelector(selector); err != nil {
+			return err
+		}
+	}
+
+	writer.Format("selector %s", strings.Join(selectors, ", "))
+	if event.UseViewTransition {
+		writer.Write("useViewTransition true")
+	}
 	return nil
 }
@@ -2,6 +2,8 @@ package datastar
 
 import (
 	"context"
+	"fmt"
+	"html/template"
 	"io"
 )
 
@@ -22,12 +24,82 @@ const (
 
 // Fragment is a standard fragment renderer.
 // Examples are: gomponents, gostar.
+// Use FragmentFunc, HTML and Template to adapt other renderers.
 type Fragment interface {
 	Render(w io.Writer) error
 }
 
 // CtxFragment is a ctx aware fragment renderer.
 // Examples are: templ.
+// Use CtxFragmentFunc to adapt other renderers.
 type CtxFragment interface {
 	Render(ctx context.Context, w io.Writer) error
 }
+
+// FromFragment adapts Fragment to CtxFragment, so it can be mixed with ctx aware fragments in a single event.
+// Context is ignored by the adapted fragment.
+func FromFragment(fragment Fragment) CtxFragment {
+	return fragmentAdapter{fragment}
+}
+
+// FromFragments adapts a slice of Fragment to CtxFragment, see FromFragment.
+func FromFragments[F Fragment](fragments ...F) []CtxFragment {
+	result := make([]CtxFragment, 0, len(fragments))
+	for _, fragment := range fragments {
+		result = append(result, FromFragment(fragment))
+	}
+	return result
+}
+
+type fragmentAdapter struct {
+	fragment Fragment
+}
+
+func (adapter fragmentAdapter) Render(_ context.Context, w io.Writer) error {
+	return adapter.fragment.Render(w)
+}
+
+// renderFragment renders a fragment to w, wrapping it's error.
+func renderFragment(ctx context.Context, w io.Writer, fragment CtxFragment) error {
+	if err := fragment.Render(ctx, w); err != nil {
+		return fmt.Errorf("render fragment: %w", err)
+	}
+	return nil
+}
+
+// FragmentFunc adapts a function to Fragment interface.
+type FragmentFunc func(w io.Writer) error
+
+// Render calls fn(w).
+func (fn FragmentFunc) Render(w io.Writer) error {
+	return fn(w)
+}
+
+// CtxFragmentFunc adapts a function to CtxFragment interface.
+type CtxFragmentFunc func(ctx context.Context, w io.Writer) error
+
+// Render calls fn(ctx, w).
+func (fn CtxFragmentFunc) Render(ctx context.Context, w io.Writer) error {
+	return fn(ctx, w)
+}
+
+// HTML is a fragment of raw html, it is sent as is.
+// Never put user input into HTML, use a renderer that escapes it, like html/template.
+type HTML string
+
+// Render writes html to w.
+func (html HTML) Render(w io.Writer) error {
+	_, err := io.WriteString(w, string(html))
+	return err
+}
+
+// Template returns a fragment that executes a html/template with data.
+// Name selects a template to execute with t.ExecuteTemplate, empty name executes t itself.
+func Template(t *template.Template, name string, data any) Fragment {
+	return FragmentFunc(func(w io.Writer) error {
+		if name == "" {
+			return t.Execute(w, data)
+		}
+		return t.ExecuteTemplate(w, name, data)
+	})
+}
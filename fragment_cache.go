@@ -0,0 +1,396 @@
+package datastar
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/awryme/datastar-go/bufpool"
+)
+
+// FragmentCache stores rendered fragments for CachedFragment.
+// Implementations must be safe for concurrent use.
+//
+// Stored data is already encoded as sse data lines and must be returned as is.
+type FragmentCache interface {
+	// Get returns data stored with key, if it's not expired.
+	Get(key string) (data []byte, ok bool)
+
+	// Generation returns current invalidation generation, it must change on every Invalidate and InvalidateTags call.
+	// It is taken before a fragment is rendered and passed to Set.
+	Generation() uint64
+
+	// Set stores data with key and tags for ttl, zero ttl means no expiration.
+	// Data must not be modified after Set.
+	//
+	// Set must drop data if key or any of tags were invalidated after generation was taken,
+	// so a render that started before invalidation doesn't store stale data.
+	Set(key string, data []byte, ttl time.Duration, tags []string, generation uint64)
+
+	// Invalidate removes entries with any of keys.
+	Invalidate(keys ...string)
+
+	// InvalidateTags removes entries with any of tags.
+	InvalidateTags(tags ...string)
+}
+
+// DefaultFragmentCache is an in-memory cache used by CachedFragment, unless WithFragmentCache option is set.
+var DefaultFragmentCache = NewMemoryFragmentCache()
+
+// CachedFragment wraps a fragment to render it once and send cached result for ttl, zero ttl means no expiration.
+// Use FromFragment to cache a Fragment.
+//
+// Key must identify the rendered content, like "nav:" + user.Role or "table:" + version.
+// Tags allow to invalidate a group of fragments at once, see FragmentCache.
+// Concurrent requests for the same missing key render the fragment only once.
+//
+// Fragments are cached in the form sent by EventMergeFragments, minified if WithMinifyFragments is set.
+// Cached data is rendered with options of the request that rendered it first and is sent to all requests as is,
+// so use the same WithMinifyFragments setting for a cache, or include it in the key.
+// CachedFragment is rendered without cache when used outside of EventMergeFragments.
+func CachedFragment(key string, ttl time.Duration, fragment CtxFragment, tags ...string) CtxFragment {
+	return cachedFragment{
+		key:      key,
+		ttl:      ttl,
+		tags:     tags,
+		fragment: fragment,
+	}
+}
+
+type cachedFragment struct {
+	key      string
+	ttl      time.Duration
+	tags     []string
+	fragment CtxFragment
+}
+
+// Render renders the fragment without cache.
+func (cached cachedFragment) Render(ctx context.Context, w io.Writer) error {
+	return cached.fragment.Render(ctx, w)
+}
+
+// load returns cached fragment encoded as sse data lines, rendering it on cache miss.
+func (cached cachedFragment) load(ctx context.Context, opts *options) ([]byte, error) {
+	cache := opts.fragmentCache
+	if data, ok := cache.Get(cached.key); ok {
+		return data, nil
+	}
+
+	return fragmentRenders.do(ctx, renderKey{cache: cache, key: cached.key}, func(ctx context.Context) ([]byte, error) {
+		// taken before rendering, so cache drops the result if it's invalidated in the meantime
+		generation := cache.Generation()
+
+		// other render could have finished before this one started
+		if data, ok := cache.Get(cached.key); ok {
+			return data, nil
+		}
+
+		buf := bufpool.GetBuffer()
+		defer bufpool.PutBuffer(buf)
+
+		if err := renderHTML(ctx, opts, buf, cached.fragment); err != nil {
+			return nil, fmt.Errorf("render cached fragment %s: %w", cached.key, err)
+		}
+
+		encoded := new(bytes.Buffer)
+		encodeFragmentLines(encoded, buf.Bytes())
+		data := encoded.Bytes()
+		cache.Set(cached.key, data, cached.ttl, cached.tags, generation)
+		return data, nil
+	})
+}
+
+// fragmentRenders deduplicates concurrent renders of cached fragments.
+var fragmentRenders renderGroup
+
+// renderKey identifies a render, same key in different caches is rendered separately.
+type renderKey struct {
+	cache FragmentCache
+	key   string
+}
+
+// renderGroup runs only one render per key at a time, other callers wait for it's result.
+type renderGroup struct {
+	mu    sync.Mutex
+	calls map[renderKey]*renderCall
+}
+
+type renderCall struct {
+	done     chan struct{}
+	data     []byte
+	err      error
+	canceled bool
+}
+
+// do runs render with ctx, or waits for a render of the same key that's already running.
+// Waiters stop waiting when their ctx is done, and render again if the running render failed because it's caller was canceled.
+// Caches of non comparable types are rendered without deduplication.
+func (group *renderGroup) do(ctx context.Context, key renderKey, render func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	if !reflect.TypeOf(key.cache).Comparable() {
+		return render(ctx)
+	}
+
+	for {
+		group.mu.Lock()
+		call, ok := group.calls[key]
+		if !ok {
+			break
+		}
+		group.mu.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		}
+		if call.canceled {
+			continue
+		}
+		return call.data, call.err
+	}
+
+	if group.calls == nil {
+		group.calls = make(map[renderKey]*renderCall)
+	}
+	// render is retried by waiters if it panics
+	call := &renderCall{done: make(chan struct{}), canceled: true}
+	group.calls[key] = call
+	group.mu.Unlock()
+
+	defer func() {
+		group.mu.Lock()
+		delete(group.calls, key)
+		group.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.data, call.err = render(ctx)
+	// failure of a canceled caller says nothing about the render, so waiters retry it as well
+	call.canceled = call.err != nil && ctx.Err() != nil
+	return call.data, call.err
+}
+
+// DefaultMemoryCacheSize is the default limit of data size in MemoryFragmentCache, in bytes.
+const DefaultMemoryCacheSize = 64 << 20
+
+// memoryCacheInvalidations limits the number of invalidated keys and tags remembered to drop stale sets.
+// Sets that started before a forgotten invalidation are dropped as well, so the limit never keeps stale data.
+const memoryCacheInvalidations = 1024
+
+// MemoryFragmentCacheOption configures MemoryFragmentCache.
+type MemoryFragmentCacheOption func(cache *MemoryFragmentCache)
+
+// WithMemoryCacheSize limits total size of cached data in bytes, least recently used entries are removed to fit new ones.
+// Zero or negative size disables the limit. DefaultMemoryCacheSize is used by default.
+func WithMemoryCacheSize(size int) MemoryFragmentCacheOption {
+	return func(cache *MemoryFragmentCache) {
+		cache.maxSize = size
+	}
+}
+
+// MemoryFragmentCache is an in-memory FragmentCache with a size limit.
+// Expired entries are removed when they are read, and periodically when new entries are added.
+type MemoryFragmentCache struct {
+	mu        sync.Mutex
+	entries   map[string]*list.Element
+	recent    *list.List
+	tags      map[string]map[string]struct{}
+	size      int
+	maxSize   int
+	sweepSize int
+
+	generation      uint64
+	invalidatedKeys map[string]uint64
+	invalidatedTags map[string]uint64
+	invalidations   []memoryCacheInvalidation
+	forgotten       uint64
+}
+
+type memoryCacheEntry struct {
+	key     string
+	data    []byte
+	expires time.Time
+	tags    []string
+}
+
+func (entry *memoryCacheEntry) expired(now time.Time) bool {
+	return !entry.expires.IsZero() && now.After(entry.expires)
+}
+
+type memoryCacheInvalidation struct {
+	name       string
+	tag        bool
+	generation uint64
+}
+
+// NewMemoryFragmentCache creates an empty MemoryFragmentCache.
+func NewMemoryFragmentCache(opts ...MemoryFragmentCacheOption) *MemoryFragmentCache {
+	cache := &MemoryFragmentCache{
+		entries:         make(map[string]*list.Element),
+		recent:          list.New(),
+		tags:            make(map[string]map[string]struct{}),
+		maxSize:         DefaultMemoryCacheSize,
+		invalidatedKeys: make(map[string]uint64),
+		invalidatedTags: make(map[string]uint64),
+	}
+	for _, opt := range opts {
+		opt(cache)
+	}
+	return cache
+}
+
+func (cache *MemoryFragmentCache) Get(key string) ([]byte, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	elem, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*memoryCacheEntry)
+	if entry.expired(time.Now()) {
+		cache.remove(key)
+		return nil, false
+	}
+	cache.recent.MoveToFront(elem)
+	return entry.data, true
+}
+
+func (cache *MemoryFragmentCache) Generation() uint64 {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	return cache.generation
+}
+
+func (cache *MemoryFragmentCache) Set(key string, data []byte, ttl time.Duration, tags []string, generation uint64) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.stale(key, tags, generation) {
+		return
+	}
+
+	cache.remove(key)
+	if cache.maxSize > 0 && len(data) > cache.maxSize {
+		return
+	}
+
+	entry := &memoryCacheEntry{
+		key:  key,
+		data: data,
+		tags: tags,
+	}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	cache.entries[key] = cache.recent.PushFront(entry)
+	cache.size += len(data)
+	for _, tag := range tags {
+		if cache.tags[tag] == nil {
+			cache.tags[tag] = make(map[string]struct{})
+		}
+		cache.tags[tag][key] = struct{}{}
+	}
+
+	// sweep when cache doubles in size, so cost is amortized over sets
+	if len(cache.entries) > 2*cache.sweepSize {
+		cache.sweep(time.Now())
+		cache.sweepSize = len(cache.entries)
+	}
+
+	for cache.maxSize > 0 && cache.size > cache.maxSize {
+		cache.remove(cache.recent.Back().Value.(*memoryCacheEntry).key)
+	}
+}
+
+// stale reports whether key or any of tags were invalidated after generation.
+func (cache *MemoryFragmentCache) stale(key string, tags []string, generation uint64) bool {
+	if generation < cache.forgotten || cache.invalidatedKeys[key] > generation {
+		return true
+	}
+	for _, tag := range tags {
+		if cache.invalidatedTags[tag] > generation {
+			return true
+		}
+	}
+	return false
+}
+
+func (cache *MemoryFragmentCache) Invalidate(keys ...string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.generation++
+	for _, key := range keys {
+		cache.remove(key)
+		cache.invalidated(key, false)
+	}
+}
+
+func (cache *MemoryFragmentCache) InvalidateTags(tags ...string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.generation++
+	for _, tag := range tags {
+		for key := range cache.tags[tag] {
+			cache.remove(key)
+		}
+		cache.invalidated(tag, true)
+	}
+}
+
+// invalidated remembers the current generation for a key or a tag, forgetting the oldest records over the limit.
+func (cache *MemoryFragmentCache) invalidated(name string, tag bool) {
+	records := cache.invalidatedKeys
+	if tag {
+		records = cache.invalidatedTags
+	}
+	records[name] = cache.generation
+	cache.invalidations = append(cache.invalidations, memoryCacheInvalidation{name: name, tag: tag, generation: cache.generation})
+
+	for len(cache.invalidations) > memoryCacheInvalidations {
+		oldest := cache.invalidations[0]
+		cache.invalidations = cache.invalidations[1:]
+
+		records := cache.invalidatedKeys
+		if oldest.tag {
+			records = cache.invalidatedTags
+		}
+		if records[oldest.name] == oldest.generation {
+			delete(records, oldest.name)
+		}
+		cache.forgotten = oldest.generation
+	}
+}
+
+func (cache *MemoryFragmentCache) remove(key string) {
+	elem, ok := cache.entries[key]
+	if !ok {
+		return
+	}
+	entry := elem.Value.(*memoryCacheEntry)
+	cache.recent.Remove(elem)
+	delete(cache.entries, key)
+	cache.size -= len(entry.data)
+	for _, tag := range entry.tags {
+		delete(cache.tags[tag], key)
+		if len(cache.tags[tag]) == 0 {
+			delete(cache.tags, tag)
+		}
+	}
+}
+
+func (cache *MemoryFragmentCache) sweep(now time.Time) {
+	for key, elem := range cache.entries {
+		if elem.Value.(*memoryCacheEntry).expired(now) {
+			cache.remove(key)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package datastar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// countingFragment counts renders, blocking on wait if it's set.
+type countingFragment struct {
+	html    string
+	renders *atomic.Int32
+	wait    chan struct{}
+}
+
+func (fragment countingFragment) Render(ctx context.Context, w io.Writer) error {
+	fragment.renders.Add(1)
+	if fragment.wait != nil {
+		<-fragment.wait
+	}
+	_, err := io.WriteString(w, fragment.html)
+	return err
+}
+
+func TestCachedFragment(t *testing.T) {
+	send := func(cache FragmentCache, events ...Event) string {
+		resp := httptest.NewRecorder()
+		ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil), WithFragmentCache(cache))
+		defer release()
+
+		if err := ds.Send(events...); err != nil {
+			t.Error(err)
+		}
+		return resp.Body.String()
+	}
+
+	t.Run("cached lines", func(t *testing.T) {
+		is := is.New(t)
+
+		cache := NewMemoryFragmentCache()
+		renders := new(atomic.Int32)
+		fragment := countingFragment{html: "<ul id=\"nav\">\n<li>a</li>\n</ul>", renders: renders}
+		event := MergeCtxFragments(FromFragment(testFragment(`<div id="a"></div>`)), CachedFragment("nav", 0, fragment))
+
+		expected := "event: datastar-merge-fragments\n" +
+			"data: fragments <div id=\"a\"></div>\n" +
+			"data: fragments <ul id=\"nav\">\n" +
+			"data: fragments <li>a</li>\n" +
+			"data: fragments </ul>\n\n"
+		is.Equal(send(cache, event), expected)
+		is.Equal(send(cache, event), expected)
+		is.Equal(renders.Load(), int32(1)) // fragment should be rendered once
+
+		data, ok := cache.Get("nav")
+		is.True(ok)
+		is.Equal(string(data), "fragments <ul id=\"nav\">\ndata: fragments <li>a</li>\ndata: fragments </ul>")
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		is := is.New(t)
+
+		cache := NewMemoryFragmentCache()
+		renders := new(atomic.Int32)
+		event := MergeCtxFragments(
+			CachedFragment("a", 0, countingFragment{html: "a", renders: renders}, "nav"),
+			CachedFragment("b", 0, countingFragment{html: "b", renders: renders}, "nav", "table"),
+			CachedFragment("c", time.Millisecond, countingFragment{html: "c", renders: renders}),
+		)
+
+		send(cache, event)
+		is.Equal(renders.Load(), int32(3))
+
+		cache.Invalidate("a")
+		send(cache, event)
+		is.Equal(renders.Load(), int32(4)) // invalidated key should be rendered again
+
+		time.Sleep(2 * time.Millisecond)
+		send(cache, event)
+		is.Equal(renders.Load(), int32(5)) // expired key should be rendered again
+
+		cache.InvalidateTags("nav")
+		send(cache, event)
+		is.Equal(renders.Load(), int32(7)) // tagged keys should be rendered again
+		is.Equal(len(cache.tags), 2)
+	})
+
+	t.Run("single render", func(t *testing.T) {
+		is := is.New(t)
+
+		cache := NewMemoryFragmentCache()
+		renders := new(atomic.Int32)
+		fragment := countingFragment{html: "x", renders: renders, wait: make(chan struct{})}
+		event := MergeCtxFragments(CachedFragment("slow", 0, fragment))
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				send(cache, event)
+			}()
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(fragment.wait)
+		wg.Wait()
+
+		is.Equal(renders.Load(), int32(1)) // concurrent requests should render once
+	})
+
+	t.Run("same key in different caches", func(t *testing.T) {
+		is := is.New(t)
+
+		cacheA, cacheB := NewMemoryFragmentCache(), NewMemoryFragmentCache()
+		renders := new(atomic.Int32)
+		fragmentA := countingFragment{html: "a", renders: renders, wait: make(chan struct{})}
+		fragmentB := countingFragment{html: "b", renders: renders}
+
+		done := make(chan string)
+		go func() {
+			done <- send(cacheA, MergeCtxFragments(CachedFragment("nav", 0, fragmentA)))
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		// render of cache A is still running, it must not be shared with cache B
+		doneB := make(chan string)
+		go func() {
+			doneB <- send(cacheB, MergeCtxFragments(CachedFragment("nav", 0, fragmentB)))
+		}()
+		select {
+		case body := <-doneB:
+			is.Equal(body, "event: datastar-merge-fragments\ndata: fragments b\n\n")
+		case <-time.After(time.Second):
+			close(fragmentA.wait)
+			t.Fatal("cache B waits for render of cache A")
+		}
+		close(fragmentA.wait)
+		is.Equal(<-done, "event: datastar-merge-fragments\ndata: fragments a\n\n")
+
+		dataA, _ := cacheA.Get("nav")
+		dataB, _ := cacheB.Get("nav")
+		is.Equal(string(dataA), "fragments a")
+		is.Equal(string(dataB), "fragments b")
+	})
+
+	t.Run("canceled render", func(t *testing.T) {
+		is := is.New(t)
+
+		cache := NewMemoryFragmentCache()
+		renders := new(atomic.Int32)
+		event := MergeCtxFragments(CachedFragment("slow", 0, CtxFragmentFunc(func(ctx context.Context, w io.Writer) error {
+			if renders.Add(1) == 1 {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			_, err := io.WriteString(w, "x")
+			return err
+		})))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		leaderErr := make(chan error)
+		go func() {
+			req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+			ds, release := New(httptest.NewRecorder(), req, WithFragmentCache(cache))
+			defer release()
+			leaderErr <- ds.Send(event)
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		waiter := make(chan string)
+		go func() {
+			waiter <- send(cache, event)
+		}()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		is.True(errors.Is(<-leaderErr, context.Canceled))                            // canceled request should fail
+		is.Equal(<-waiter, "event: datastar-merge-fragments\ndata: fragments x\n\n") // waiting request should render again
+		is.Equal(renders.Load(), int32(2))
+	})
+
+	t.Run("invalidate while rendering", func(t *testing.T) {
+		is := is.New(t)
+
+		for _, invalidate := range []func(cache *MemoryFragmentCache){
+			func(cache *MemoryFragmentCache) { cache.Invalidate("nav") },
+			func(cache *MemoryFragmentCache) { cache.InvalidateTags("menu") },
+		} {
+			cache := NewMemoryFragmentCache()
+			renders := new(atomic.Int32)
+			fragment := countingFragment{html: "old", renders: renders, wait: make(chan struct{})}
+
+			done := make(chan string)
+			go func() {
+				done <- send(cache, MergeCtxFragments(CachedFragment("nav", 0, fragment, "menu")))
+			}()
+			time.Sleep(10 * time.Millisecond)
+
+			invalidate(cache)
+			close(fragment.wait)
+			is.Equal(<-done, "event: datastar-merge-fragments\ndata: fragments old\n\n")
+
+			_, ok := cache.Get("nav")
+			is.True(!ok) // render started before invalidation should not be cached
+		}
+	})
+
+	t.Run("respond", func(t *testing.T) {
+		is := is.New(t)
+
+		cache := NewMemoryFragmentCache()
+		renders := new(atomic.Int32)
+		fragment := CachedFragment("nav", 0, countingFragment{html: "<p>\na</p>", renders: renders})
+
+		for range 2 {
+			resp := httptest.NewRecorder()
+			ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil), WithFragmentCache(cache))
+			is.NoErr(ds.RespondCtxFragments(fragment))
+			release()
+			is.Equal(resp.Body.String(), "<p>\na</p>")
+		}
+		is.Equal(renders.Load(), int32(1))
+	})
+}
+
+func TestMemoryFragmentCache(t *testing.T) {
+	t.Run("size limit", func(t *testing.T) {
+		is := is.New(t)
+
+		cache := NewMemoryFragmentCache(WithMemoryCacheSize(10))
+		cache.Set("a", []byte("aaaa"), 0, []string{"x"}, cache.Generation())
+		cache.Set("b", []byte("bbbb"), 0, nil, cache.Generation())
+		_, ok := cache.Get("a") // a is used recently, so b is removed first
+		is.True(ok)
+
+		cache.Set("c", []byte("cccc"), 0, nil, cache.Generation())
+		_, ok = cache.Get("b")
+		is.True(!ok) // least recently used entry should be removed
+		_, ok = cache.Get("a")
+		is.True(ok)
+		is.Equal(cache.size, 8)
+
+		cache.Set("d", []byte("dddddddddddd"), 0, nil, cache.Generation())
+		_, ok = cache.Get("d")
+		is.True(!ok) // entry over the limit should not be cached
+
+		cache.Invalidate("a", "c")
+		is.Equal(cache.size, 0)
+		is.Equal(len(cache.tags), 0)
+	})
+
+	t.Run("forgotten invalidations", func(t *testing.T) {
+		is := is.New(t)
+
+		cache := NewMemoryFragmentCache()
+		generation := cache.Generation()
+		for idx := range memoryCacheInvalidations + 1 {
+			cache.Invalidate(fmt.Sprint("key", idx))
+		}
+		is.Equal(len(cache.invalidatedKeys), memoryCacheInvalidations)
+
+		cache.Set("key0", []byte("a"), 0, nil, generation)
+		_, ok := cache.Get("key0")
+		is.True(!ok) // set started before forgotten invalidation should be dropped
+
+		cache.Set("key0", []byte("a"), 0, nil, cache.Generation())
+		_, ok = cache.Get("key0")
+		is.True(ok)
+	})
+}
@@ -0,0 +1,99 @@
+package datastar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestParallelRender(t *testing.T) {
+	send := func(event Event, opts ...Option) (string, error) {
+		resp := httptest.NewRecorder()
+		ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil), opts...)
+		defer release()
+
+		err := ds.Send(event)
+		return resp.Body.String(), err
+	}
+
+	t.Run("order and limit", func(t *testing.T) {
+		is := is.New(t)
+
+		var running, maxRunning atomic.Int32
+		event := MergeCtxFragments()
+		expected := "event: datastar-merge-fragments\n"
+		for idx := range 8 {
+			html := fmt.Sprintf(`<div id="f%d"></div>`, idx)
+			expected += "data: fragments " + html + "\n"
+			event.Fragments = append(event.Fragments, CtxFragmentFunc(func(ctx context.Context, w io.Writer) error {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for {
+					current := maxRunning.Load()
+					if n <= current || maxRunning.CompareAndSwap(current, n) {
+						break
+					}
+				}
+
+				// later fragments finish first
+				time.Sleep(time.Duration(8-idx) * time.Millisecond)
+				_, err := io.WriteString(w, html)
+				return err
+			}))
+		}
+		event.Fragments = append(event.Fragments, FromFragment(HTML("<p>\nlast</p>")))
+		expected += "data: fragments <p>\ndata: fragments last</p>\n\n"
+
+		body, err := send(event, WithParallelRender(3))
+		is.NoErr(err)
+		is.Equal(body, expected)
+		is.True(maxRunning.Load() <= 3) // renders should respect the limit
+		is.True(maxRunning.Load() > 1)  // fragments should render concurrently
+	})
+
+	t.Run("error cancels", func(t *testing.T) {
+		is := is.New(t)
+
+		renderErr := errors.New("db failed")
+		var canceled atomic.Bool
+		event := MergeCtxFragments(
+			CtxFragmentFunc(func(ctx context.Context, w io.Writer) error {
+				select {
+				case <-ctx.Done():
+					canceled.Store(true)
+					return ctx.Err()
+				case <-time.After(time.Second):
+					return nil
+				}
+			}),
+			FromFragment(FragmentFunc(func(w io.Writer) error {
+				return renderErr
+			})),
+		)
+
+		body, err := send(event, WithParallelRender(2))
+		is.True(errors.Is(err, renderErr)) // first error should be returned
+		is.True(canceled.Load())           // other renders should be canceled
+		is.Equal(body, "")
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		is := is.New(t)
+
+		event := MergeFragments(HTML("a"), FragmentFunc(func(w io.Writer) error {
+			panic("boom")
+		}))
+
+		_, err := send(event, WithParallelRender(2))
+		is.True(err != nil && strings.Contains(err.Error(), "boom")) // panic should become an error
+	})
+}
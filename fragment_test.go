@@ -0,0 +1,91 @@
+package datastar
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+type ctxKey struct{}
+
+func TestFragmentAdapters(t *testing.T) {
+	is := is.New(t)
+
+	tmpl := template.Must(template.New("page").Parse(`{{define "row"}}<div id="row">{{.}}</div>{{end}}<main>{{.}}</main>`))
+	fn := FragmentFunc(func(w io.Writer) error {
+		_, err := io.WriteString(w, "<p>fn</p>")
+		return err
+	})
+	ctxFn := CtxFragmentFunc(func(ctx context.Context, w io.Writer) error {
+		_, err := io.WriteString(w, ctx.Value(ctxKey{}).(string))
+		return err
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), ctxKey{}, "<p>ctx</p>"))
+	resp := httptest.NewRecorder()
+	ds, release := New(resp, req)
+	defer release()
+
+	event := MergeFragments(
+		Template(tmpl, "row", "<script>"),
+		Template(tmpl, "", "main"),
+		HTML("<p>html</p>"),
+		fn,
+	)
+	event.Fragments = append(event.Fragments, ctxFn)
+	is.NoErr(ds.Send(event))
+	is.Equal(resp.Body.String(), "event: datastar-merge-fragments\n"+
+		"data: fragments <div id=\"row\">&lt;script&gt;</div>\n"+
+		"data: fragments <main>main</main>\n"+
+		"data: fragments <p>html</p>\n"+
+		"data: fragments <p>fn</p>\n"+
+		"data: fragments <p>ctx</p>\n\n")
+
+	err := ds.Send(MergeFragments(Template(tmpl, "missing", nil)))
+	is.True(err != nil) // missing template should fail
+}
+
+func TestMixedFragments(t *testing.T) {
+	send := func(event Event) (string, error) {
+		resp := httptest.NewRecorder()
+		ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+		defer release()
+
+		err := ds.Send(event)
+		return resp.Body.String(), err
+	}
+
+	t.Run("mixed order", func(t *testing.T) {
+		is := is.New(t)
+
+		event := MergeCtxFragments(FromFragment(testFragment("<li>1</li>")), testCtxFragment("<li>2</li>"), FromFragment(testFragment("<li>3</li>")), testCtxFragment("<li>4</li>"))
+		event.Selector = "#list"
+		event.MergeMode = ModeAppend
+		body, err := send(event)
+		is.NoErr(err)
+		is.Equal(body, "event: datastar-merge-fragments\n"+
+			"data: selector #list\n"+
+			"data: mergeMode append\n"+
+			"data: fragments <li>1</li>\n"+
+			"data: fragments <li>2</li>\n"+
+			"data: fragments <li>3</li>\n"+
+			"data: fragments <li>4</li>\n\n")
+	})
+
+	t.Run("respond mixed order", func(t *testing.T) {
+		is := is.New(t)
+
+		resp := httptest.NewRecorder()
+		ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+		defer release()
+
+		is.NoErr(ds.RespondCtxFragments(testCtxFragment("<li>1</li>"), FromFragment(testFragment("<li>2</li>"))))
+		is.Equal(resp.Body.String(), "<li>1</li><li>2</li>")
+	})
+}
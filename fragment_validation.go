@@ -0,0 +1,125 @@
+package datastar
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ErrInvalidFragment is returned when fragment validation is enabled and a fragment can't be merged by id.
+var ErrInvalidFragment = fmt.Errorf("invalid fragment")
+
+// FragmentValidationMode sets how merged fragments are validated.
+// Validation is meant for development and tests, it parses every rendered fragment.
+type FragmentValidationMode int
+
+const (
+	FragmentValidationOff   FragmentValidationMode = iota //Fragments are not validated. This is the default.
+	FragmentValidationWarn                                //Invalid fragments are logged with slog.Default and sent anyway.
+	FragmentValidationError                               //Invalid fragments fail the event with ErrInvalidFragment.
+)
+
+// fragmentValidator checks fragments of a single event.
+// Without a selector Datastar merges fragments into elements with the same id,
+// so every top-level element must have an id. Ids must be unique across the event.
+type fragmentValidator struct {
+	mode      FragmentValidationMode
+	requireID bool
+	ids       map[string]bool
+}
+
+func newFragmentValidator(mode FragmentValidationMode, selector string) *fragmentValidator {
+	if mode == FragmentValidationOff {
+		return nil
+	}
+	return &fragmentValidator{
+		mode:      mode,
+		requireID: selector == "",
+		ids:       make(map[string]bool),
+	}
+}
+
+// validate checks a rendered fragment and handles the error according to validation mode.
+func (validator *fragmentValidator) validate(ctx context.Context, fragment string) error {
+	if validator == nil {
+		return nil
+	}
+
+	err := validator.check(fragment)
+	if err != nil && validator.mode == FragmentValidationWarn {
+		slog.WarnContext(ctx, "datastar fragment validation failed", "error", err)
+		return nil
+	}
+	return err
+}
+
+// check parses a fragment like a browser does, with implied end tags, and checks top-level nodes and ids.
+// Fragment is parsed in template context, so table rows and cells stay top-level elements.
+func (validator *fragmentValidator) check(fragment string) error {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "template",
+		DataAtom: atom.Template,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: parse html: %w", ErrInvalidFragment, err)
+	}
+
+	for _, node := range nodes {
+		if validator.requireID {
+			switch {
+			case node.Type == html.TextNode && strings.TrimSpace(node.Data) != "":
+				return fmt.Errorf("%w: top-level text %q can't be merged without a selector", ErrInvalidFragment, shortText(node.Data))
+			case node.Type == html.ElementNode && nodeID(node) == "":
+				return fmt.Errorf("%w: top-level <%s> has no id, set an id or a selector", ErrInvalidFragment, node.Data)
+			}
+		}
+
+		if err := validator.checkID(node); err != nil {
+			return err
+		}
+		for child := range node.Descendants() {
+			if err := validator.checkID(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkID checks that element id is unique across the event.
+func (validator *fragmentValidator) checkID(node *html.Node) error {
+	id := nodeID(node)
+	if id == "" {
+		return nil
+	}
+	if validator.ids[id] {
+		return fmt.Errorf("%w: duplicate id %q", ErrInvalidFragment, id)
+	}
+	validator.ids[id] = true
+	return nil
+}
+
+func nodeID(node *html.Node) string {
+	if node.Type != html.ElementNode {
+		return ""
+	}
+	for _, attr := range node.Attr {
+		if attr.Namespace == "" && attr.Key == "id" {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func shortText(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) > 20 {
+		return text[:20] + "..."
+	}
+	return text
+}
@@ -0,0 +1,94 @@
+package datastar
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestFragmentValidation(t *testing.T) {
+	runTest := func(name string, selector string, fragments []string, expectedErr error) {
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+
+			resp := httptest.NewRecorder()
+			ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil), WithFragmentValidation(FragmentValidationError))
+			defer release()
+
+			event := MergeCtxFragments()
+			for _, fragment := range fragments {
+				event.Fragments = append(event.Fragments, FromFragment(HTML(fragment)))
+			}
+			event.Selector = selector
+
+			err := ds.Send(event)
+			is.True(errors.Is(err, expectedErr)) // error should be expected
+		})
+	}
+
+	runTest("ok: top-level ids",
+		"",
+		[]string{
+			`<!-- row --><div id="a"><p>text</p><br><img src="x"/></div>`,
+			"\n<section ID='b' class=x><script>if (a < b) { document.write('</div><div>') }</script></section>\n",
+			`<input id=c disabled><svg id="d"><path d="M0"/></svg>`,
+		},
+		nil,
+	)
+
+	runTest("ok: table rows",
+		"",
+		[]string{`<tr id="row-1"><td>1</td></tr>`, `<td id="cell"><p>a<p>b</td>`},
+		nil,
+	)
+
+	runTest("ok: selector without ids",
+		"#list",
+		[]string{`<li>1</li>`, `text<li>2</li>`},
+		nil,
+	)
+
+	runTest("fail: top-level element without id",
+		"",
+		[]string{`<div id="a"></div><div class="b"><span id="c"></span></div>`},
+		ErrInvalidFragment,
+	)
+
+	runTest("fail: implied end tag",
+		"",
+		[]string{`<p id="p">a<p>b`},
+		ErrInvalidFragment,
+	)
+
+	runTest("fail: top-level text",
+		"",
+		[]string{`<div id="a"></div> text`},
+		ErrInvalidFragment,
+	)
+
+	runTest("fail: duplicate id in event",
+		"",
+		[]string{`<div id="a"></div>`, `<div id="b"><span id="a"></span></div>`},
+		ErrInvalidFragment,
+	)
+
+	runTest("fail: duplicate id with selector",
+		"#list",
+		[]string{`<li id="a"></li><li id="a"></li>`},
+		ErrInvalidFragment,
+	)
+
+	t.Run("warn", func(t *testing.T) {
+		is := is.New(t)
+
+		resp := httptest.NewRecorder()
+		ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil), WithFragmentValidation(FragmentValidationWarn))
+		defer release()
+
+		is.NoErr(ds.Send(MergeFragments(HTML("<div></div>"))))
+		is.Equal(resp.Body.String(), "event: datastar-merge-fragments\ndata: fragments <div></div>\n\n")
+	})
+}
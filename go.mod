@@ -6,4 +6,5 @@ require (
 	github.com/awryme/sse-go v0.0.0-20250420213924-fedd2fae5f1e
 	github.com/matryer/is v1.4.1
 	github.com/valyala/fastjson v1.6.4
+	golang.org/x/net v0.50.0
 )
@@ -0,0 +1,158 @@
+package datastar
+
+import (
+	"bytes"
+	"strings"
+)
+
+// preservedElements keep their content as is when minifying.
+var preservedElements = map[string]bool{
+	"pre": true, "textarea": true, "script": true, "style": true,
+	"title": true, "xmp": true, "plaintext": true, "listing": true,
+}
+
+// minifyHTML writes html with collapsed whitespace to dst.
+// Each whitespace run in text and between attributes becomes a single space,
+// preserved elements, comments and quoted attribute values are kept as is.
+// It doesn't add or remove nodes, so parsed tree is the same apart from whitespace in text.
+func minifyHTML(dst *bytes.Buffer, html []byte) {
+	dst.Grow(len(html))
+	for len(html) > 0 {
+		switch {
+		case !isMarkup(html):
+			end := indexMarkup(html)
+			writeCollapsed(dst, html[:end])
+			html = html[end:]
+
+		case bytes.HasPrefix(html, []byte("<!--")):
+			end := bytes.Index(html[4:], []byte("-->"))
+			if end < 0 {
+				dst.Write(html)
+				return
+			}
+			end += 4 + 3
+			dst.Write(html[:end])
+			html = html[end:]
+
+		default:
+			var name string
+			name, html = minifyTag(dst, html)
+			if preservedElements[name] {
+				end := indexEndTag(html, name)
+				dst.Write(html[:end])
+				html = html[end:]
+			}
+		}
+	}
+}
+
+// isMarkup reports whether html starts with a tag or a comment.
+func isMarkup(html []byte) bool {
+	return len(html) > 1 && html[0] == '<' && (isLetter(rune(html[1])) || html[1] == '/' || bytes.HasPrefix(html, []byte("<!--")))
+}
+
+// indexMarkup returns the index of the next tag or comment in html, or len(html) if there is none.
+func indexMarkup(html []byte) int {
+	for idx := 1; ; idx++ {
+		next := bytes.IndexByte(html[idx:], '<')
+		if next < 0 {
+			return len(html)
+		}
+		idx += next
+		if isMarkup(html[idx:]) {
+			return idx
+		}
+	}
+}
+
+// minifyTag writes a tag with collapsed whitespace to dst, returning the start tag name and html after the tag.
+// End tags have an empty name.
+func minifyTag(dst *bytes.Buffer, html []byte) (name string, rest []byte) {
+	end := bytes.IndexAny(html[1:], " \t\n\r\f/>") + 1
+	if end == 0 {
+		end = len(html)
+	}
+	if html[1] != '/' {
+		name = strings.ToLower(string(html[1:end]))
+	}
+	dst.Write(html[:end])
+	html = html[end:]
+
+	var last byte
+	for len(html) > 0 {
+		c := html[0]
+		switch {
+		case c == '>':
+			dst.WriteByte(c)
+			return name, html[1:]
+
+		case isSpace(c):
+			html = html[spaceRun(html):]
+			dst.WriteByte(' ')
+			continue
+
+		case (c == '"' || c == '\'') && last == '=':
+			end := bytes.IndexByte(html[1:], c)
+			if end < 0 {
+				dst.Write(html)
+				return name, nil
+			}
+			end += 2
+			dst.Write(html[:end])
+			html = html[end:]
+			last = c
+			continue
+		}
+
+		dst.WriteByte(c)
+		html = html[1:]
+		last = c
+	}
+	return name, nil
+}
+
+// indexEndTag returns the index of the end tag of element name, or len(html) if there is none.
+func indexEndTag(html []byte, name string) int {
+	for idx := 0; ; {
+		next := bytes.Index(html[idx:], []byte("</"))
+		if next < 0 {
+			return len(html)
+		}
+		idx += next
+		tag := html[idx+2:]
+		if len(tag) >= len(name) && strings.EqualFold(string(tag[:len(name)]), name) &&
+			(len(tag) == len(name) || isSpace(tag[len(name)]) || tag[len(name)] == '>' || tag[len(name)] == '/') {
+			return idx
+		}
+		idx += 2
+	}
+}
+
+// writeCollapsed writes text to dst with each whitespace run replaced by a single space.
+func writeCollapsed(dst *bytes.Buffer, text []byte) {
+	for len(text) > 0 {
+		if n := spaceRun(text); n > 0 {
+			dst.WriteByte(' ')
+			text = text[n:]
+			continue
+		}
+		end := bytes.IndexAny(text, " \t\n\r\f")
+		if end < 0 {
+			end = len(text)
+		}
+		dst.Write(text[:end])
+		text = text[end:]
+	}
+}
+
+func spaceRun(html []byte) int {
+	n := 0
+	for n < len(html) && isSpace(html[n]) {
+		n++
+	}
+	return n
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,166 @@
+package datastar
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func TestMinifyHTML(t *testing.T) {
+	runTest := func(name, html, expected string) {
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+
+			buf := new(bytes.Buffer)
+			minifyHTML(buf, []byte(html))
+			is.Equal(buf.String(), expected)
+			is.NoErr(compareHTMLTrees(html, buf.String())) // minified html should have the same tree
+		})
+	}
+
+	runTest("text and tags",
+		"<div  id=\"a\"\n  class=\"b\">\n\t<p>hello\n   world</p>\n</div>\n",
+		`<div id="a" class="b"> <p>hello world</p> </div> `,
+	)
+
+	runTest("quoted attributes",
+		"<div title=\"a\n  b\" data-x = 'c   d' data-on-click=\"$a = 1;\n$b  = 2\">x</div>",
+		"<div title=\"a\n  b\" data-x = 'c   d' data-on-click=\"$a = 1;\n$b  = 2\">x</div>",
+	)
+
+	runTest("preserved elements",
+		"<pre>\n  a\n    b\n</pre>  <textarea>\n x  </textarea>\n<script>\nif (a  <  b) {\n}\n</script>\n<STYLE>\n a {  }\n</STYLE>",
+		"<pre>\n  a\n    b\n</pre> <textarea>\n x  </textarea> <script>\nif (a  <  b) {\n}\n</script> <STYLE>\n a {  }\n</STYLE>",
+	)
+
+	runTest("pre with tags",
+		"<pre><b>a  </b>\n  c</pre>\n\n<p>  d</p>",
+		"<pre><b>a  </b>\n  c</pre> <p> d</p>",
+	)
+
+	runTest("comments and text",
+		"<!-- a\n   b --> a < b ",
+		"<!-- a\n   b --> a < b ",
+	)
+
+	runTest("inline elements",
+		"<p>a <b>b</b>\n\t<i>c</i> , d</p>\n",
+		"<p>a <b>b</b> <i>c</i> , d</p> ",
+	)
+
+	runTest("unclosed",
+		"<pre>\n a",
+		"<pre>\n a",
+	)
+}
+
+func TestMinifyHTMLTree(t *testing.T) {
+	is := is.New(t)
+
+	buf := new(bytes.Buffer)
+	minifyHTML(buf, []byte(benchmarkFragment))
+	is.True(buf.Len() < len(benchmarkFragment))
+	is.Equal(strings.Count(buf.String(), "\n"), 0) // indented text should fit in a single line
+	is.NoErr(compareHTMLTrees("<table>"+benchmarkFragment+"</table>", "<table>"+buf.String()+"</table>"))
+}
+
+// compareHTMLTrees parses html fragments and compares their trees.
+// Text nodes must be present in both trees, but may have different whitespace runs, unless they are in preserved elements.
+func compareHTMLTrees(expected, actual string) error {
+	parse := func(data string) ([]*html.Node, error) {
+		return html.ParseFragment(strings.NewReader(data), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	}
+	expectedNodes, err := parse(expected)
+	if err != nil {
+		return err
+	}
+	actualNodes, err := parse(actual)
+	if err != nil {
+		return err
+	}
+	return compareHTMLNodes(expectedNodes, actualNodes, false)
+}
+
+var whitespaceRuns = regexp.MustCompile(`[ \t\n\r\f]+`)
+
+func compareHTMLNodes(expected, actual []*html.Node, preserved bool) error {
+	if len(expected) != len(actual) {
+		return fmt.Errorf("got %d nodes, expected %d", len(actual), len(expected))
+	}
+	for idx, node := range expected {
+		other := actual[idx]
+		if node.Type != other.Type || node.Namespace != other.Namespace || !slices.Equal(node.Attr, other.Attr) {
+			return fmt.Errorf("node %q differs from %q", other.Data, node.Data)
+		}
+		data, otherData := node.Data, other.Data
+		if node.Type == html.TextNode && !preserved {
+			data, otherData = whitespaceRuns.ReplaceAllString(data, " "), whitespaceRuns.ReplaceAllString(otherData, " ")
+		}
+		if data != otherData {
+			return fmt.Errorf("node %q differs from %q", other.Data, node.Data)
+		}
+		childrenPreserved := preserved || (node.Type == html.ElementNode && preservedElements[node.Data])
+		if err := compareHTMLNodes(slices.Collect(node.ChildNodes()), slices.Collect(other.ChildNodes()), childrenPreserved); err != nil {
+			return fmt.Errorf("%s: %w", node.Data, err)
+		}
+	}
+	return nil
+}
+
+func TestMinifyFragments(t *testing.T) {
+	is := is.New(t)
+
+	resp := httptest.NewRecorder()
+	ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil), WithMinifyFragments(true))
+	defer release()
+
+	is.NoErr(ds.Send(MergeFragments(HTML("<ul id=\"list\">\n  <li>1</li>\n  <li>2</li>\n</ul>\n"))))
+	is.Equal(resp.Body.String(), "event: datastar-merge-fragments\ndata: fragments <ul id=\"list\"> <li>1</li> <li>2</li> </ul> \n\n")
+}
+
+var benchmarkFragment = strings.Repeat(`
+<tr id="row-1" class="row">
+	<td class="name">
+		John Doe
+	</td>
+	<td>
+		<button data-on-click="@delete('/rows/1')">
+			Delete
+		</button>
+	</td>
+</tr>
+`, 100)
+
+func BenchmarkMinifyFragments(b *testing.B) {
+	run := func(name string, opts ...Option) {
+		b.Run(name, func(b *testing.B) {
+			event := MergeFragments(HTML(benchmarkFragment))
+			event.Selector = "#rows"
+
+			var size, lines int
+			for b.Loop() {
+				resp := httptest.NewRecorder()
+				ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil), opts...)
+				if err := ds.Send(event); err != nil {
+					b.Fatal(err)
+				}
+				release()
+				size, lines = resp.Body.Len(), strings.Count(resp.Body.String(), "\n")
+			}
+			b.ReportMetric(float64(size), "payload-bytes")
+			b.ReportMetric(float64(lines), "payload-lines")
+		})
+	}
+
+	run("plain")
+	run("minified", WithMinifyFragments(true))
+}
@@ -0,0 +1,235 @@
+package datastar
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// options contain Datastar settings, they are set with Option functions.
+type options struct {
+	retry   time.Duration
+	eventID func() string
+	codec   JSONCodec
+
+	writeTimeout     time.Duration
+	closeSlowClients bool
+
+	debugEvents        bool
+	nonceFunc          func(r *http.Request) string
+	fragmentValidation FragmentValidationMode
+	minifyFragments    bool
+	fragmentCache      FragmentCache
+	parallelRender     int
+
+	filter          signalFilter
+	maxSignalsSize  int64
+	maxSignalsDepth int
+}
+
+func newOptions(opts []Option) options {
+	options := options{
+		codec:           StdJSONCodec,
+		fragmentCache:   DefaultFragmentCache,
+		maxSignalsSize:  DefaultMaxSignalsSize,
+		maxSignalsDepth: DefaultMaxSignalsDepth,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+type optionsKey struct{}
+
+// optionsFromRequest returns options of the Datastar instance that is sending events.
+// Events get options this way, because they receive only the request.
+func optionsFromRequest(req *http.Request) *options {
+	if opts, ok := req.Context().Value(optionsKey{}).(*options); ok {
+		return opts
+	}
+	opts := newOptions(nil)
+	return &opts
+}
+
+// Option configures a Datastar instance.
+// Options are passed to New or used to create a Config.
+type Option func(opts *options)
+
+// WithRetry sets sse retry field for every event.
+// See https://developer.mozilla.org/en-US/docs/Web/API/Server-sent_events/Using_server-sent_events#retry
+func WithRetry(retry time.Duration) Option {
+	return func(opts *options) {
+		opts.retry = retry
+	}
+}
+
+// WithEventIDs sets sse id field for every event, next is called to generate id for each event.
+// Client sends the last received id in Last-Event-ID header when reconnecting, use Datastar.LastEventID to read it.
+func WithEventIDs(next func() string) Option {
+	return func(opts *options) {
+		opts.eventID = next
+	}
+}
+
+// WithWriteTimeout sets a deadline for writing each sse event, so a stalled client can't block Send forever.
+// Exceeded deadline fails Send with ErrSlowClient. Zero value disables the deadline, this is the default.
+//
+// It uses http.ResponseController, response writer must support SetWriteDeadline, or Send fails.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.writeTimeout = timeout
+	}
+}
+
+// WithCloseSlowClients closes sse stream when write deadline set by WithWriteTimeout is exceeded.
+// Closed stream fails every following Send with ErrSlowClient without rendering events,
+// handlers should return when they get ErrSlowClient.
+func WithCloseSlowClients(enabled bool) Option {
+	return func(opts *options) {
+		opts.closeSlowClients = enabled
+	}
+}
+
+// WithJSONCodec sets a codec to marshal sent signals and unmarshal request signals.
+// Nil codec resets it to StdJSONCodec.
+func WithJSONCodec(codec JSONCodec) Option {
+	if codec == nil {
+		codec = StdJSONCodec
+	}
+	return func(opts *options) {
+		opts.codec = codec
+	}
+}
+
+// WithDebugEvents mirrors every sent event to browser console with console.debug, along with it's size in bytes.
+// It's meant for development and is disabled by default, enable it with a dev flag, like WithDebugEvents(isDev).
+func WithDebugEvents(enabled bool) Option {
+	return func(opts *options) {
+		opts.debugEvents = enabled
+	}
+}
+
+// WithFragmentValidation sets how merged fragments are validated.
+// Validation catches fragments that Datastar can't merge, like top-level elements without ids when there is no selector, or duplicate ids.
+// It's meant for development and tests, enable it with a dev flag, like WithFragmentValidation(FragmentValidationError) in tests.
+// Refer to individual FragmentValidationMode constants for details.
+func WithFragmentValidation(mode FragmentValidationMode) Option {
+	return func(opts *options) {
+		opts.fragmentValidation = mode
+	}
+}
+
+// WithMinifyFragments collapses insignificant whitespace in merged fragments before sending.
+// Each whitespace run in text, between tags and between attributes becomes a single space,
+// so fragments take less bytes and indented html fits in a single sse data line.
+// Content of pre, textarea, script and style elements, comments and quoted attribute values is kept as is.
+//
+// Minified fragments have the same DOM tree, only text nodes have collapsed whitespace.
+// They render the same, unless your css uses `white-space: pre`, `pre-wrap` or `pre-line` on other elements.
+func WithMinifyFragments(enabled bool) Option {
+	return func(opts *options) {
+		opts.minifyFragments = enabled
+	}
+}
+
+// WithFragmentCache sets a cache to store fragments created with CachedFragment.
+// Nil cache resets it to DefaultFragmentCache.
+func WithFragmentCache(cache FragmentCache) Option {
+	if cache == nil {
+		cache = DefaultFragmentCache
+	}
+	return func(opts *options) {
+		opts.fragmentCache = cache
+	}
+}
+
+// WithParallelRender renders fragments of a single event concurrently, at most limit at a time.
+// It helps when fragments are slow to render, like templ components that query a database.
+// Fragments are still sent in the original order. First render error cancels the context of other renders.
+// Limit of one or less renders fragments sequentially, this is the default.
+func WithParallelRender(limit int) Option {
+	return func(opts *options) {
+		opts.parallelRender = limit
+	}
+}
+
+// WithNonceFunc sets a function to get CSP nonce for scripts sent with EventExecuteScript.
+// By default nonce is read with NonceFromContext.
+func WithNonceFunc(nonce func(r *http.Request) string) Option {
+	return func(opts *options) {
+		opts.nonceFunc = nonce
+	}
+}
+
+// WithLocalSignals sets how local signals (with names starting with "_") are handled when reading request signals.
+// Refer to individual LocalSignalsMode constants for details.
+func WithLocalSignals(mode LocalSignalsMode) Option {
+	return func(opts *options) {
+		opts.filter.local = mode
+	}
+}
+
+// WithAllowedSignals sets a list of signal paths accepted by the handler, like "user.name" or "user".
+// Allowing a path allows all of it's children.
+// Requests with any other signals are rejected with ErrSignalNotAllowed.
+// All signals are allowed by default.
+func WithAllowedSignals(paths ...string) Option {
+	return func(opts *options) {
+		for _, path := range paths {
+			opts.filter.allowed = append(opts.filter.allowed, strings.Split(path, signalSeparator))
+		}
+	}
+}
+
+// WithMaxSignalsSize limits the size of signals payload in query or body, in bytes.
+// Multipart forms are limited as a whole, files included, unless files are read with StreamFiles first.
+// Zero value uses DefaultMaxSignalsSize, negative value disables the limit.
+func WithMaxSignalsSize(size int64) Option {
+	return func(opts *options) {
+		opts.maxSignalsSize = limitValue(size, DefaultMaxSignalsSize)
+	}
+}
+
+// WithMaxSignalsDepth limits nesting of objects and arrays in signals.
+// Zero value uses DefaultMaxSignalsDepth, negative value disables the limit.
+func WithMaxSignalsDepth(depth int) Option {
+	return func(opts *options) {
+		opts.maxSignalsDepth = limitValue(depth, DefaultMaxSignalsDepth)
+	}
+}
+
+// Config is an app-wide set of options.
+// It creates preconfigured Datastar instances for each request.
+//
+// Config is immutable and safe for concurrent use.
+type Config struct {
+	opts []Option
+}
+
+// NewConfig creates a new Config with provided options.
+func NewConfig(opts ...Option) Config {
+	return Config{
+		opts: slices.Clone(opts),
+	}
+}
+
+// With returns a copy of Config with additional options.
+func (cfg Config) With(opts ...Option) Config {
+	return Config{
+		opts: slices.Concat(cfg.opts, opts),
+	}
+}
+
+// New creates a new Datastar instance with Config options, followed by provided options.
+// Refer to New function for details.
+func (cfg Config) New(w http.ResponseWriter, r *http.Request, opts ...Option) (ds *Datastar, release func()) {
+	return New(w, r, slices.Concat(cfg.opts, opts)...)
+}
+
+// SignalsMiddleware creates a SignalsMiddleware with Config options, followed by provided options.
+// Refer to SignalsMiddleware function for details.
+func (cfg Config) SignalsMiddleware(opts ...Option) func(next http.Handler) http.Handler {
+	return SignalsMiddleware(slices.Concat(cfg.opts, opts)...)
+}
@@ -0,0 +1,35 @@
+package datastar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestConfig(t *testing.T) {
+	is := is.New(t)
+
+	id := 0
+	cfg := NewConfig(
+		WithRetry(time.Second),
+		WithEventIDs(func() string {
+			id++
+			return strconv.Itoa(id)
+		}),
+	)
+
+	resp := httptest.NewRecorder()
+	ds, release := cfg.With(WithRetry(2*time.Second)).New(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+	defer release()
+
+	is.NoErr(ds.Send(RemoveSignals("a")))
+	is.NoErr(ds.Send(RemoveSignals("b")))
+
+	expected := "id: 1\nretry: 2000\nevent: datastar-remove-signals\ndata: paths a\n\n" +
+		"id: 2\nretry: 2000\nevent: datastar-remove-signals\ndata: paths b\n\n"
+	is.Equal(resp.Body.String(), expected)
+}
@@ -0,0 +1,98 @@
+package datastar
+
+import "slices"
+
+// Patch creates a new PatchBuilder to update multiple parts of the page at once.
+//
+//	ds.Send(datastar.Patch().
+//		Append("#rows", row).
+//		Morph(counter).
+//		Inner("#status", status).
+//		Events()...)
+func Patch() *PatchBuilder {
+	return &PatchBuilder{}
+}
+
+// PatchBuilder collects fragments with their own selectors and merge modes into a sequence of events.
+// Send the events with a single Datastar.Send call, so they are flushed together and applied at once.
+//
+// Consecutive fragments with the same selector and mode are merged into a single event.
+type PatchBuilder struct {
+	events []Event
+}
+
+// Morph merges fragments into elements with matching ids, using ModeMorph.
+func (patch *PatchBuilder) Morph(fragments ...CtxFragment) *PatchBuilder {
+	return patch.merge("", "", fragments)
+}
+
+// Inner replaces innerHTML of the element matching selector with fragments.
+func (patch *PatchBuilder) Inner(selector string, fragments ...CtxFragment) *PatchBuilder {
+	return patch.merge(selector, ModeInner, fragments)
+}
+
+// Outer replaces outerHTML of the element matching selector with fragments.
+func (patch *PatchBuilder) Outer(selector string, fragments ...CtxFragment) *PatchBuilder {
+	return patch.merge(selector, ModeOuter, fragments)
+}
+
+// Prepend prepends fragments to children of the element matching selector.
+func (patch *PatchBuilder) Prepend(selector string, fragments ...CtxFragment) *PatchBuilder {
+	return patch.merge(selector, ModePrepend, fragments)
+}
+
+// Append appends fragments to children of the element matching selector.
+func (patch *PatchBuilder) Append(selector string, fragments ...CtxFragment) *PatchBuilder {
+	return patch.merge(selector, ModeAppend, fragments)
+}
+
+// Before inserts fragments before the element matching selector, as siblings.
+func (patch *PatchBuilder) Before(selector string, fragments ...CtxFragment) *PatchBuilder {
+	return patch.merge(selector, ModeBefore, fragments)
+}
+
+// After inserts fragments after the element matching selector, as siblings.
+func (patch *PatchBuilder) After(selector string, fragments ...CtxFragment) *PatchBuilder {
+	return patch.merge(selector, ModeAfter, fragments)
+}
+
+// Remove removes elements matching any of selectors.
+func (patch *PatchBuilder) Remove(selectors ...string) *PatchBuilder {
+	patch.events = append(patch.events, RemoveFragments(selectors...))
+	return patch
+}
+
+// Add adds any event to the patch, like a fully configured EventMergeFragments or EventMergeSignals.
+func (patch *PatchBuilder) Add(events ...Event) *PatchBuilder {
+	patch.events = append(patch.events, events...)
+	return patch
+}
+
+// Events returns events in the order they were added.
+func (patch *PatchBuilder) Events() []Event {
+	return patch.events
+}
+
+func (patch *PatchBuilder) merge(selector string, mode MergeMode, fragments []CtxFragment) *PatchBuilder {
+	if len(fragments) == 0 {
+		return patch
+	}
+
+	// merge with the previous event if it targets the same elements the same way
+	if last := len(patch.events) - 1; last >= 0 {
+		if event, ok := patch.events[last].(EventMergeFragments); ok &&
+			event.Selector == selector && event.MergeMode == mode && !event.UseViewTransition {
+			// event may be added with Add, clip it's fragments so appending copies them instead of writing into caller's slice
+			event.Fragments = append(slices.Clip(event.Fragments), fragments...)
+			patch.events[last] = event
+			return patch
+		}
+	}
+
+	// fragments may be the caller's variadic slice, appending to the event must not write into it
+	event := MergeCtxFragments(slices.Clone(fragments)...)
+	event.Selector = selector
+	event.MergeMode = mode
+	patch.events = append(patch.events, event)
+	return patch
+}
@@ -0,0 +1,78 @@
+package datastar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+type countingWriter struct {
+	*httptest.ResponseRecorder
+	writes int
+}
+
+func (w *countingWriter) Write(data []byte) (int, error) {
+	w.writes++
+	return w.ResponseRecorder.Write(data)
+}
+
+func TestPatch(t *testing.T) {
+	is := is.New(t)
+
+	resp := &countingWriter{ResponseRecorder: httptest.NewRecorder()}
+	ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+	defer release()
+
+	patch := Patch().
+		Append("#rows", FromFragment(testFragment("<li>1</li>"))).
+		Append("#rows", testCtxFragment("<li>2</li>")).
+		Morph(FromFragment(testFragment(`<span id="count">2</span>`))).
+		Inner("#status", FromFragment(testFragment("ok"))).
+		Remove("#loading").
+		Add(RemoveSignals("loading"))
+	is.Equal(len(patch.Events()), 5) // consecutive appends should be merged
+
+	is.NoErr(ds.Send(patch.Events()...))
+	is.Equal(resp.writes, 1) // events should be written at once
+	is.Equal(resp.Body.String(), strings.Join([]string{
+		"event: datastar-merge-fragments\ndata: selector #rows\ndata: mergeMode append\ndata: fragments <li>1</li>\ndata: fragments <li>2</li>\n",
+		"event: datastar-merge-fragments\ndata: fragments <span id=\"count\">2</span>\n",
+		"event: datastar-merge-fragments\ndata: selector #status\ndata: mergeMode inner\ndata: fragments ok\n",
+		"event: datastar-remove-fragments\ndata: selector #loading\n",
+		"event: datastar-remove-signals\ndata: paths loading\n",
+	}, "\n")+"\n")
+}
+
+func TestPatchCallerSlices(t *testing.T) {
+	is := is.New(t)
+
+	fragments := make([]CtxFragment, 1, 2)
+	fragments[0] = testCtxFragment("<li>1</li>")
+	added := MergeCtxFragments(make([]CtxFragment, 1, 2)...)
+	added.Fragments[0] = testCtxFragment("<li>3</li>")
+
+	Patch().
+		Append("#rows", fragments...).
+		Append("#rows", testCtxFragment("<li>2</li>")).
+		Add(added).
+		Morph(testCtxFragment("<li>4</li>"))
+	is.Equal(fragments[:2][1], nil)       // caller's variadic slice should not be changed
+	is.Equal(added.Fragments[:2][1], nil) // fragments of added event should not be changed
+}
+
+func TestSendAtomic(t *testing.T) {
+	is := is.New(t)
+
+	resp := httptest.NewRecorder()
+	ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+	defer release()
+
+	err := ds.Send(RemoveSignals("a"), RemoveFragments("[b"))
+	is.True(err != nil)          // invalid event should fail
+	is.Equal(resp.Body.Len(), 0) // nothing should be sent on failure
+
+	is.NoErr(ds.Send()) // empty send is a no-op
+}
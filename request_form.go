@@ -0,0 +1,208 @@
+package datastar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"slices"
+)
+
+// defaultMultipartMemory is used to parse multipart forms when signals size limit is disabled.
+const defaultMultipartMemory = 32 << 20
+
+// ErrFilesRead is returned when StreamFiles is called more than once.
+var ErrFilesRead = fmt.Errorf("form files were already read")
+
+// FormFile is a file uploaded with a multipart form.
+type FormFile struct {
+	// Field is the name of the form field.
+	Field string
+
+	// Filename is the name of the file provided by the client.
+	Filename string
+
+	// Header contains the file part headers, like Content-Type.
+	Header textproto.MIMEHeader
+
+	// Size is the file size in bytes, or -1 if the file is streamed and size is unknown.
+	Size int64
+
+	// Reader reads the file content.
+	io.Reader
+}
+
+// StreamFiles calls fn for every file uploaded with a multipart form, in order of appearance.
+// File content can be read only inside of fn.
+//
+// If signals were not read yet, request body is streamed without buffering files.
+// Other form values are collected along the way, so UnmarshalSignals can be used afterwards.
+// If signals or http.Request.MultipartForm were already read, files are read from buffers made by http.Request.ParseMultipartForm.
+//
+// Signals size limit applies differently in these cases.
+// Streamed files don't count toward the limit, only form values do, fn decides how much of each file to read.
+// Buffered files count toward the limit, as the whole body is read before StreamFiles is called.
+//
+// StreamFiles can be called only once per request.
+func (signals *RequestSignals) StreamFiles(fn func(file FormFile) error) error {
+	if signals.shared != nil {
+		// request body is owned by shared signals
+		return signals.shared.StreamFiles(fn)
+	}
+
+	if signals.filesRead {
+		return ErrFilesRead
+	}
+	signals.filesRead = true
+
+	mediaType, err := signalsMediaType(signals.req.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	if mediaType != contentTypeMultipart {
+		return fmt.Errorf("%w: files require %s, got %s", ErrContentType, contentTypeMultipart, mediaType)
+	}
+
+	if signals.readErr != nil {
+		return signals.readErr
+	}
+	if signals.form != nil || signals.req.MultipartForm != nil {
+		// form was parsed already, by these signals or by other code like CSRF middleware
+		return signals.readBufferedFiles(fn)
+	}
+
+	if err := signals.streamMultipart(fn); err != nil {
+		// body is partially read, so the error is final
+		signals.readErr = err
+		return err
+	}
+	return nil
+}
+
+func (signals *RequestSignals) readBufferedFiles(fn func(file FormFile) error) error {
+	form := signals.req.MultipartForm
+	if form == nil {
+		return nil
+	}
+
+	for _, field := range slices.Sorted(maps.Keys(form.File)) {
+		for _, header := range form.File[field] {
+			err := readBufferedFile(field, header, fn)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func readBufferedFile(field string, header *multipart.FileHeader, fn func(file FormFile) error) error {
+	file, err := header.Open()
+	if err != nil {
+		return fmt.Errorf("open form file %s: %w", field, err)
+	}
+	defer file.Close()
+
+	return fn(FormFile{
+		Field:    field,
+		Filename: header.Filename,
+		Header:   header.Header,
+		Size:     header.Size,
+		Reader:   file,
+	})
+}
+
+func (signals *RequestSignals) streamMultipart(fn func(file FormFile) error) error {
+	reader, err := signals.req.MultipartReader()
+	if err != nil {
+		return fmt.Errorf("read multipart form: %w", err)
+	}
+
+	limit := signals.opts.maxSignalsSize
+	values := make(url.Values)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read multipart form: %w", err)
+		}
+
+		if part.FileName() == "" {
+			data, err := readLimited(part, limit)
+			if err != nil {
+				return fmt.Errorf("read form value %s: %w", part.FormName(), err)
+			}
+			if limit >= 0 {
+				limit -= int64(len(data))
+			}
+			values.Add(part.FormName(), string(data))
+			continue
+		}
+
+		err = fn(FormFile{
+			Field:    part.FormName(),
+			Filename: part.FileName(),
+			Header:   part.Header,
+			Size:     -1,
+			Reader:   part,
+		})
+		part.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	signals.form = values
+	return nil
+}
+
+func (signals *RequestSignals) readForm(limit int64) error {
+	data, err := signals.readBody(limit)
+	if err != nil {
+		return err
+	}
+
+	signals.form, err = url.ParseQuery(string(data))
+	if err != nil {
+		return fmt.Errorf("parse form signals: %w", err)
+	}
+	return nil
+}
+
+// readMultipartForm buffers the whole multipart form with http.Request.ParseMultipartForm.
+// Files are buffered too, so they count toward the limit along with form values.
+func (signals *RequestSignals) readMultipartForm(limit int64) error {
+	maxMemory := limit
+	if maxMemory < 0 {
+		maxMemory = defaultMultipartMemory
+	} else {
+		if err := checkSize(signals.req.ContentLength, limit); err != nil {
+			return err
+		}
+		// ParseMultipartForm limits only memory, files over it are written to disk
+		signals.req.Body = http.MaxBytesReader(nil, signals.req.Body, limit)
+	}
+
+	err := signals.req.ParseMultipartForm(maxMemory)
+	var maxBytesErr *http.MaxBytesError
+	if errors.Is(err, multipart.ErrMessageTooLarge) || errors.As(err, &maxBytesErr) {
+		return fmt.Errorf("%w: %w", ErrSignalsTooLarge, err)
+	}
+	if err != nil {
+		return fmt.Errorf("parse multipart form signals: %w", err)
+	}
+
+	values := maps.Clone(signals.req.MultipartForm.Value)
+	if err := checkSize(formSize(values), limit); err != nil {
+		return err
+	}
+
+	signals.form = values
+	return nil
+}
@@ -0,0 +1,225 @@
+package datastar
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+type formUser struct {
+	Name    string   `form:"name"`
+	Age     int      `json:"age"`
+	Admin   bool     `form:"admin"`
+	Tags    []string `form:"tags"`
+	Address struct {
+		City string `form:"city"`
+	} `form:"address"`
+	Score *float64
+	Skip  string `form:"-"`
+}
+
+func newFormRequest(values url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func newMultipartRequest(t *testing.T, values url.Values, files map[string]string) *http.Request {
+	t.Helper()
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	for name, fieldValues := range values {
+		for _, value := range fieldValues {
+			if err := writer.WriteField(name, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for name, content := range files {
+		part, err := writer.CreateFormFile(name, name+".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(content))
+	}
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+var testFormValues = url.Values{
+	"name":         {"john"},
+	"age":          {"42"},
+	"admin":        {"on"},
+	"tags":         {"a", "b"},
+	"address.city": {"paris"},
+	"Score":        {"1.5"},
+	"Skip":         {"skipped"},
+	"-":            {"skipped"},
+}
+
+func checkFormUser(is *is.I, user formUser) {
+	is.Equal(user.Name, "john")
+	is.Equal(user.Age, 42)
+	is.True(user.Admin)
+	is.Equal(user.Tags, []string{"a", "b"})
+	is.Equal(user.Address.City, "paris")
+	is.True(user.Score != nil)
+	is.Equal(*user.Score, 1.5)
+	is.Equal(user.Skip, "")
+}
+
+func TestUnmarshalFormSignals(t *testing.T) {
+	t.Run("urlencoded", func(t *testing.T) {
+		is := is.New(t)
+
+		ds, release := New(httptest.NewRecorder(), newFormRequest(testFormValues))
+		defer release()
+
+		var user formUser
+		is.NoErr(ds.UnmarshalSignals(&user))
+		checkFormUser(is, user)
+
+		var city string
+		is.NoErr(ds.UnmarshalSignals(&city, "address", "city"))
+		is.Equal(city, "paris")
+
+		var address map[string]any
+		is.NoErr(ds.UnmarshalSignals(&address, "address"))
+		is.Equal(address, map[string]any{"city": "paris"})
+
+		err := ds.UnmarshalSignals(&city, "missing")
+		is.True(err != nil) // missing signal should fail
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		is := is.New(t)
+
+		req := newMultipartRequest(t, testFormValues, map[string]string{"avatar": "image data"})
+		ds, release := New(httptest.NewRecorder(), req)
+		defer release()
+
+		var user formUser
+		is.NoErr(ds.UnmarshalSignals(&user))
+		checkFormUser(is, user)
+
+		files := make(map[string]string)
+		err := ds.StreamFiles(func(file FormFile) error {
+			data, err := io.ReadAll(file)
+			files[file.Filename] = string(data)
+			is.Equal(file.Size, int64(len(data)))
+			return err
+		})
+		is.NoErr(err)
+		is.Equal(files, map[string]string{"avatar.txt": "image data"})
+
+		err = ds.StreamFiles(func(file FormFile) error { return nil })
+		is.Equal(err, ErrFilesRead) // files can be read only once
+	})
+
+	t.Run("multipart streamed", func(t *testing.T) {
+		is := is.New(t)
+
+		req := newMultipartRequest(t, testFormValues, map[string]string{"avatar": "image data"})
+		ds, release := New(httptest.NewRecorder(), req)
+		defer release()
+
+		files := make(map[string]string)
+		err := ds.StreamFiles(func(file FormFile) error {
+			data, err := io.ReadAll(file)
+			files[file.Filename] = string(data)
+			is.Equal(file.Size, int64(-1))
+			return err
+		})
+		is.NoErr(err)
+		is.Equal(files, map[string]string{"avatar.txt": "image data"})
+
+		var user formUser
+		is.NoErr(ds.UnmarshalSignals(&user))
+		checkFormUser(is, user)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		is := is.New(t)
+
+		req := newSignalsRequest(`{"name": "john"}`)
+		req.Header.Set("Content-Type", "application/json")
+		ds, release := New(httptest.NewRecorder(), req)
+		defer release()
+
+		var name string
+		is.NoErr(ds.UnmarshalSignals(&name, "name"))
+		is.Equal(name, "john")
+
+		err := ds.StreamFiles(func(file FormFile) error { return nil })
+		is.True(err != nil) // files require multipart form
+	})
+
+	t.Run("filter form", func(t *testing.T) {
+		is := is.New(t)
+
+		values := url.Values{"name": {"john"}, "form._dirty": {"true"}}
+		ds, release := New(httptest.NewRecorder(), newFormRequest(values), WithLocalSignals(LocalSignalsStrip))
+		defer release()
+
+		var signals map[string]any
+		is.NoErr(ds.UnmarshalSignals(&signals))
+		is.Equal(signals, map[string]any{"name": "john"})
+	})
+}
+
+func TestMultipartFormLimit(t *testing.T) {
+	files := map[string]string{"avatar": strings.Repeat("x", 5<<20)}
+
+	t.Run("content length", func(t *testing.T) {
+		is := is.New(t)
+
+		req := newMultipartRequest(t, url.Values{"name": {"john"}}, files)
+		ds, release := New(httptest.NewRecorder(), req, WithMaxSignalsSize(1024))
+		defer release()
+
+		var name string
+		err := ds.UnmarshalSignals(&name, "name")
+		is.True(errors.Is(err, ErrSignalsTooLarge)) // files should count toward the limit
+	})
+
+	t.Run("unknown length", func(t *testing.T) {
+		is := is.New(t)
+
+		req := newMultipartRequest(t, url.Values{"name": {"john"}}, files)
+		req.ContentLength = -1
+		ds, release := New(httptest.NewRecorder(), req, WithMaxSignalsSize(1024))
+		defer release()
+
+		var name string
+		err := ds.UnmarshalSignals(&name, "name")
+		is.True(errors.Is(err, ErrSignalsTooLarge)) // body should be limited while reading
+	})
+
+	t.Run("streamed files", func(t *testing.T) {
+		is := is.New(t)
+
+		req := newMultipartRequest(t, url.Values{"name": {"john"}}, files)
+		ds, release := New(httptest.NewRecorder(), req, WithMaxSignalsSize(1024))
+		defer release()
+
+		is.NoErr(ds.StreamFiles(func(file FormFile) error {
+			_, err := io.Copy(io.Discard, file)
+			return err
+		})) // streamed files should not count toward the limit
+
+		var name string
+		is.NoErr(ds.UnmarshalSignals(&name, "name"))
+		is.Equal(name, "john")
+	})
+}
@@ -0,0 +1,363 @@
+package datastar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/valyala/fastjson"
+)
+
+var parserPool fastjson.ParserPool
+
+// RequestSignals reads and decodes signals sent with the request.
+//
+// Signals are read once per request.
+// Use SignalsMiddleware to store them in request context, so every layer (middleware or handler) can read them.
+// Datastar instances use signals from context if they are present.
+//
+// RequestSignals is not safe for concurrent use.
+type RequestSignals struct {
+	opts options
+	req  *http.Request
+
+	// shared signals are read by SignalsMiddleware, this instance applies handler options on top of them
+	shared *RequestSignals
+
+	body       []byte
+	rawData    []byte
+	jsonData   *fastjson.Value
+	jsonParser *fastjson.Parser
+	form       url.Values
+	filtered   bool
+	filesRead  bool
+	readErr    error
+}
+
+func newRequestSignals(r *http.Request, opts options) *RequestSignals {
+	return &RequestSignals{
+		opts: opts,
+		req:  r,
+	}
+}
+
+// withOptions returns a view of signals with opts applied on top of the options signals were read with.
+// View shares request data, but checks it against it's own size and depth limits, filters and decodes it on it's own.
+// Filters are applied after the filters of shared signals, so both must allow a signal.
+// Caller must release returned signals.
+func (signals *RequestSignals) withOptions(opts []Option) *RequestSignals {
+	viewOpts := signals.opts
+	viewOpts.filter = signalFilter{}
+	for _, opt := range opts {
+		opt(&viewOpts)
+	}
+
+	return &RequestSignals{
+		opts:   viewOpts,
+		req:    signals.req,
+		shared: signals,
+	}
+}
+
+// release puts fastjson parser back to the pool.
+func (signals *RequestSignals) release() {
+	if signals.jsonParser != nil {
+		parserPool.Put(signals.jsonParser)
+		signals.jsonParser = nil
+		signals.jsonData = nil
+	}
+}
+
+type signalsKey struct{}
+
+// SignalsFromContext returns request signals stored in context by SignalsMiddleware.
+// It returns nil if there are no signals in context.
+func SignalsFromContext(ctx context.Context) *RequestSignals {
+	signals, _ := ctx.Value(signalsKey{}).(*RequestSignals)
+	return signals
+}
+
+// SignalsMiddleware is a standard http middleware that reads request signals once and stores them in request context.
+// Use SignalsFromContext to get them in other middleware or handlers, Datastar instances pick them up automatically.
+//
+// Json and urlencoded bodies are read immediately and the request body is restored for downstream handlers.
+// Multipart forms are read on first access and their body is not restored, use StreamFiles or http.Request.MultipartForm.
+// Read errors are not handled by the middleware, they are returned when signals are accessed.
+//
+// Provided options configure reading signals, other options are ignored.
+// Options passed to New in handlers are applied on top of them, see New for details.
+func SignalsMiddleware(opts ...Option) func(next http.Handler) http.Handler {
+	options := newOptions(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if SignalsFromContext(r.Context()) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r, signals := withRequestSignals(r, options)
+			defer signals.release()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// withRequestSignals reads request signals and returns a copy of request with signals in context.
+// Caller must release returned signals.
+func withRequestSignals(r *http.Request, opts options) (*http.Request, *RequestSignals) {
+	signals := newRequestSignals(nil, opts)
+
+	r = r.WithContext(context.WithValue(r.Context(), signalsKey{}, signals))
+	signals.req = r
+	signals.preload()
+	return r, signals
+}
+
+// preload reads json and urlencoded signals immediately and restores the request body for other handlers.
+// Errors are saved and returned on access.
+func (signals *RequestSignals) preload() {
+	if signals.isMultipart() {
+		return
+	}
+
+	_ = signals.readRawData()
+	if signals.body != nil {
+		// body can be read partially if it's too large, the rest of it is still in the original body
+		r := signals.req
+		r.Body = readCloser{
+			Reader: io.MultiReader(bytes.NewReader(signals.body), r.Body),
+			Closer: r.Body,
+		}
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func (signals *RequestSignals) isMultipart() bool {
+	if signals.req.Method == http.MethodGet {
+		return false
+	}
+	mediaType, err := signalsMediaType(signals.req.Header.Get("Content-Type"))
+	return err == nil && mediaType == contentTypeMultipart
+}
+
+// UnmarshalSignals unmarshals a signal (or multiple) into a provided value.
+// It uses JSONCodec (encoding/json by default) to do it, so regular Unmarshal rules apply.
+// If path is provided it will find signal value at that path.
+// Path lookups are done with fastjson, found value is unmarshalled with the codec as well.
+//
+// Path can be separated by "." or be made of individual components, like: "my.data.value" or ("my", "data", "value").
+//
+// Form submissions (urlencoded or multipart) are decoded into the same values.
+// Form field names are used as "." separated signal paths, struct fields are matched by `form` tag, then by `json` tag.
+// Use StreamFiles to read uploaded files.
+//
+// Signals are checked against WithLocalSignals and WithAllowedSignals options before decoding.
+func (signals *RequestSignals) UnmarshalSignals(value any, path ...string) error {
+	// ensure we have at least raw data
+	if err := signals.readRawData(); err != nil {
+		return err
+	}
+
+	if err := signals.filterSignals(); err != nil {
+		return err
+	}
+
+	if signals.form != nil {
+		fullpath := strings.Join(path, signalSeparator)
+		found, err := decodeForm(signals.form, fullpath, value)
+		if err != nil {
+			return err
+		}
+		if !found && fullpath != "" {
+			return fmt.Errorf("%w: %s", ErrSignalNotFound, fullpath)
+		}
+		return nil
+	}
+
+	if len(path) == 0 {
+		// fast path, just unmarshal the whole object
+		return signals.opts.codec.Unmarshal(signals.rawData, value)
+	}
+
+	// slower path, use fastjson to find the value
+	jsonValue, err := signals.lookupSignal(path)
+	if err != nil {
+		return err
+	}
+
+	return signals.opts.codec.Unmarshal(jsonValue.MarshalTo(nil), value)
+}
+
+func (signals *RequestSignals) parseSignals() error {
+	if signals.jsonData != nil {
+		return nil
+	}
+
+	if err := signals.readRawData(); err != nil {
+		return err
+	}
+
+	if signals.jsonParser == nil {
+		signals.jsonParser = parserPool.Get()
+	}
+
+	var err error
+	signals.jsonData, err = signals.jsonParser.ParseBytes(signals.rawData)
+	return err
+}
+
+func (signals *RequestSignals) filterSignals() error {
+	if signals.filtered {
+		return nil
+	}
+
+	filter := signals.opts.filter
+	if !filter.enabled() {
+		signals.filtered = true
+		return nil
+	}
+
+	if signals.form != nil {
+		if err := filter.filterForm(signals.form); err != nil {
+			return err
+		}
+		signals.filtered = true
+		return nil
+	}
+
+	if err := signals.parseSignals(); err != nil {
+		return err
+	}
+
+	obj, err := signals.jsonData.Object()
+	if err != nil {
+		return fmt.Errorf("signals must be an object: %w", err)
+	}
+
+	changed, err := filter.filterObject(obj, nil, len(filter.allowed) == 0)
+	if err != nil {
+		return err
+	}
+	if changed {
+		// keep raw data in sync for the fast path
+		signals.rawData = signals.jsonData.MarshalTo(nil)
+	}
+
+	signals.filtered = true
+	return nil
+}
+
+func (signals *RequestSignals) readRawData() error {
+	if signals.rawData != nil || signals.form != nil || signals.readErr != nil {
+		return signals.readErr
+	}
+
+	if signals.shared != nil {
+		signals.readErr = signals.readShared()
+		return signals.readErr
+	}
+
+	if err := signals.readRequestData(); err != nil {
+		// body may be partially read, so the error is final
+		signals.readErr = err
+		return err
+	}
+	return nil
+}
+
+// readShared takes data read by shared signals, checking it against own limits.
+func (signals *RequestSignals) readShared() error {
+	shared := signals.shared
+	if err := shared.readRawData(); err != nil {
+		return err
+	}
+	if err := shared.filterSignals(); err != nil {
+		return err
+	}
+
+	if shared.form != nil {
+		if err := checkSize(formSize(shared.form), signals.opts.maxSignalsSize); err != nil {
+			return err
+		}
+		// own filter modifies the form
+		signals.form = maps.Clone(shared.form)
+		return nil
+	}
+
+	if err := checkSize(int64(len(shared.rawData)), signals.opts.maxSignalsSize); err != nil {
+		return err
+	}
+	// raw data is never modified, filters replace it
+	return signals.setJSONData(shared.rawData)
+}
+
+func (signals *RequestSignals) readRequestData() error {
+	limit := signals.opts.maxSignalsSize
+
+	if signals.req.Method == http.MethodGet {
+		query := signals.req.URL.Query()
+
+		data := query.Get("datastar")
+		if data == "" {
+			return fmt.Errorf("datastar query signals not found")
+		}
+
+		if err := checkSize(int64(len(data)), limit); err != nil {
+			return err
+		}
+		return signals.setJSONData([]byte(data))
+	}
+
+	mediaType, err := signalsMediaType(signals.req.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+
+	switch mediaType {
+	case contentTypeJSON:
+		data, err := signals.readBody(limit)
+		if err != nil {
+			return err
+		}
+		return signals.setJSONData(data)
+	case contentTypeForm:
+		return signals.readForm(limit)
+	case contentTypeMultipart:
+		return signals.readMultipartForm(limit)
+	default:
+		return fmt.Errorf("%w: %s", ErrContentType, mediaType)
+	}
+}
+
+func (signals *RequestSignals) readBody(limit int64) ([]byte, error) {
+	if err := checkSize(signals.req.ContentLength, limit); err != nil {
+		return nil, err
+	}
+
+	data, err := readLimited(signals.req.Body, limit)
+	// keep the body read so far to restore it for other handlers, even if it failed
+	signals.body = data
+	if err != nil {
+		return nil, fmt.Errorf("read request body signals: %w", err)
+	}
+	return data, nil
+}
+
+func (signals *RequestSignals) setJSONData(data []byte) error {
+	if err := checkDepth(data, signals.opts.maxSignalsDepth); err != nil {
+		return err
+	}
+
+	signals.rawData = data
+	return nil
+}
@@ -0,0 +1,169 @@
+package datastar
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestSignalsMiddleware(t *testing.T) {
+	is := is.New(t)
+
+	const body = `{"token": "secret", "name": "john"}`
+
+	var checked bool
+	check := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			signals := SignalsFromContext(r.Context())
+			is.True(signals != nil) // middleware should store signals in context
+
+			token, err := signals.SignalString("token")
+			is.NoErr(err)
+			is.Equal(token, "secret")
+			checked = true
+
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ds, release := New(w, r)
+		defer release()
+
+		is.Equal(ds.Signals().shared, SignalsFromContext(r.Context())) // datastar should use signals from context
+
+		var name string
+		is.NoErr(ds.UnmarshalSignals(&name, "name"))
+		is.Equal(name, "john")
+
+		data, err := io.ReadAll(r.Body)
+		is.NoErr(err)
+		is.Equal(string(data), body) // body should be restored
+	})
+
+	mw := SignalsMiddleware(WithMaxSignalsSize(1000))
+	mw(check(handler)).ServeHTTP(httptest.NewRecorder(), newSignalsRequest(body))
+	is.True(checked)
+}
+
+func TestSignalsMiddlewareError(t *testing.T) {
+	is := is.New(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signals map[string]any
+		err := SignalsFromContext(r.Context()).UnmarshalSignals(&signals)
+		is.True(errors.Is(err, ErrSignalsTooLarge)) // read error should be returned on access
+	})
+
+	mw := SignalsMiddleware(WithMaxSignalsSize(5))
+	mw(handler).ServeHTTP(httptest.NewRecorder(), newSignalsRequest(`{"name": "john"}`))
+}
+
+func TestSignalsMiddlewareLargeBody(t *testing.T) {
+	is := is.New(t)
+
+	body := `{"data": "` + strings.Repeat("x", 2<<20) + `"}`
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		is.NoErr(err)
+		is.Equal(len(data), len(body)) // body over the limit should be restored in full
+	})
+
+	// unknown length, so the body is read until the limit
+	req := httptest.NewRequest(http.MethodPost, "/", io.MultiReader(bytes.NewReader([]byte(body))))
+	req.ContentLength = -1
+	SignalsMiddleware()(handler).ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestSignalsMiddlewareHandlerOptions(t *testing.T) {
+	runTest := func(name string, mwOpts, opts []Option, body string, expected map[string]any, expectedErr error) {
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ds, release := New(w, r, opts...)
+				defer release()
+
+				var signals map[string]any
+				err := ds.UnmarshalSignals(&signals)
+				is.True(errors.Is(err, expectedErr)) // error should be expected
+				if expectedErr == nil {
+					is.Equal(signals, expected)
+				}
+
+				// shared signals should not be changed by handler options
+				var shared map[string]any
+				is.NoErr(SignalsFromContext(r.Context()).UnmarshalSignals(&shared))
+			})
+
+			SignalsMiddleware(mwOpts...)(handler).ServeHTTP(httptest.NewRecorder(), newSignalsRequest(body))
+		})
+	}
+
+	runTest("fail: handler allowed signals",
+		nil,
+		[]Option{WithAllowedSignals("name")},
+		`{"name": "john", "admin": true}`,
+		nil,
+		ErrSignalNotAllowed,
+	)
+
+	runTest("ok: handler strips local signals",
+		nil,
+		[]Option{WithLocalSignals(LocalSignalsStrip)},
+		`{"name": "john", "_dirty": true}`,
+		map[string]any{"name": "john"},
+		nil,
+	)
+
+	runTest("ok: middleware and handler filters",
+		[]Option{WithLocalSignals(LocalSignalsStrip)},
+		[]Option{WithAllowedSignals("name")},
+		`{"name": "john", "_dirty": true}`,
+		map[string]any{"name": "john"},
+		nil,
+	)
+
+	runTest("fail: handler size limit",
+		nil,
+		[]Option{WithMaxSignalsSize(5)},
+		`{"name": "john"}`,
+		nil,
+		ErrSignalsTooLarge,
+	)
+
+	runTest("fail: handler depth limit",
+		nil,
+		[]Option{WithMaxSignalsDepth(1)},
+		`{"user": {"name": "john"}}`,
+		nil,
+		ErrSignalsTooDeep,
+	)
+
+	t.Run("form", func(t *testing.T) {
+		is := is.New(t)
+
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ds, release := New(w, r, WithLocalSignals(LocalSignalsStrip))
+			defer release()
+
+			var signals map[string]any
+			is.NoErr(ds.UnmarshalSignals(&signals))
+			is.Equal(signals, map[string]any{"name": "john"})
+
+			var shared map[string]any
+			is.NoErr(SignalsFromContext(r.Context()).UnmarshalSignals(&shared))
+			is.Equal(shared, map[string]any{"name": "john", "_dirty": "true"}) // shared form should not be changed
+		})
+
+		req := newFormRequest(map[string][]string{"name": {"john"}, "_dirty": {"true"}})
+		SignalsMiddleware()(handler).ServeHTTP(httptest.NewRecorder(), req)
+	})
+}
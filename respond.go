@@ -0,0 +1,192 @@
+package datastar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/awryme/datastar-go/bufpool"
+)
+
+const (
+	headerDatastarRequest           = "Datastar-Request"
+	headerDatastarSelector          = "Datastar-Selector"
+	headerDatastarMergeMode         = "Datastar-Merge-Mode"
+	headerDatastarUseViewTransition = "Datastar-Use-View-Transition"
+	headerDatastarOnlyIfMissing     = "Datastar-Only-If-Missing"
+	headerDatastarScriptAttributes  = "Datastar-Script-Attributes"
+)
+
+// IsDatastarRequest reports whether the request was sent by datastar.
+// Datastar actions send `Datastar-Request: true` header, plain navigation and forms don't.
+func IsDatastarRequest(r *http.Request) bool {
+	return r.Header.Get(headerDatastarRequest) == "true"
+}
+
+// IsDatastarRequest reports whether the request was sent by datastar.
+// Refer to IsDatastarRequest function for details.
+func (ds *Datastar) IsDatastarRequest() bool {
+	return IsDatastarRequest(ds.req)
+}
+
+// RespondPage serves both plain and datastar requests from one handler, enabling progressive enhancement.
+// Plain requests get the full html document rendered by page.
+// Datastar requests get fragments sent as an sse event.
+//
+// Response has `Vary: Datastar-Request` header, so caches keep both versions apart.
+func (ds *Datastar) RespondPage(page CtxFragment, fragments EventMergeFragments) error {
+	ds.mu.Lock()
+	ds.resp.Header().Add("Vary", headerDatastarRequest)
+	ds.mu.Unlock()
+
+	if ds.IsDatastarRequest() {
+		return ds.Send(fragments)
+	}
+
+	buf := bufpool.GetBuffer()
+	defer bufpool.PutBuffer(buf)
+
+	// render before writing, so errors can still be handled with a proper response
+	if err := renderFragment(ds.req.Context(), buf, page); err != nil {
+		return fmt.Errorf("render page: %w", err)
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	ds.resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := ds.resp.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("write page: %w", err)
+	}
+	return nil
+}
+
+// Plain responses
+
+// Respond writes a single event as a plain (non sse) response.
+// Datastar clients merge such responses same way as events, it's useful for one-shot handlers
+// or when proxies and CDNs break event streams.
+//
+// Supported events are:
+//   - EventMergeFragments: text/html response, with selector and merge mode headers.
+//   - EventMergeSignals: application/json response, with only-if-missing header.
+//   - EventExecuteScript: text/javascript response, with script attributes header.
+//
+// Respond should be called once and must not be mixed with Send.
+func (ds *Datastar) Respond(event Event) error {
+	buf := bufpool.GetBuffer()
+	defer bufpool.PutBuffer(buf)
+
+	header := make(http.Header)
+	req := ds.eventRequest()
+
+	var err error
+	switch event := event.(type) {
+	case EventMergeFragments:
+		err = event.respond(header, buf, req)
+	case EventMergeSignals:
+		err = event.respond(header, buf, req)
+	case EventExecuteScript:
+		err = event.respond(header, buf, req)
+	default:
+		return fmt.Errorf("event %s can't be sent as a plain response", event.Name())
+	}
+	if err != nil {
+		return err
+	}
+
+	// write headers only when event is rendered, so errors can still be handled with a proper response
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for name, values := range header {
+		ds.resp.Header()[name] = values
+	}
+	if _, err := ds.resp.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("write response: %w", err)
+	}
+	return nil
+}
+
+// RespondFragments writes fragments as a plain text/html response.
+// Refer to Respond for details.
+func (ds *Datastar) RespondFragments(fragments ...Fragment) error {
+	return ds.Respond(MergeFragments(fragments...))
+}
+
+// RespondCtxFragments writes ctx aware fragments as a plain text/html response.
+// Refer to Respond for details.
+func (ds *Datastar) RespondCtxFragments(fragments ...CtxFragment) error {
+	return ds.Respond(MergeCtxFragments(fragments...))
+}
+
+// RespondSignals writes signals as a plain application/json response.
+// Signals value is transformed same way as in MergeSignals, any other value is sent as is.
+// Refer to Respond for details.
+func (ds *Datastar) RespondSignals(signals any) error {
+	if signals, ok := signals.(Signals); ok {
+		return ds.Respond(MergeSignals(signals))
+	}
+	return ds.Respond(MergeSignalsObj(signals))
+}
+
+func (event EventMergeFragments) respond(header http.Header, buf *bytes.Buffer, req *http.Request) error {
+	header.Set("Content-Type", "text/html; charset=utf-8")
+	if event.Selector != "" {
+		if err := ValidateSelector(event.Selector); err != nil {
+			return err
+		}
+		header.Set(headerDatastarSelector, event.Selector)
+	}
+	if event.MergeMode != "" {
+		header.Set(headerDatastarMergeMode, string(event.MergeMode))
+	}
+	if event.UseViewTransition {
+		header.Set(headerDatastarUseViewTransition, "true")
+	}
+
+	return event.renderFragments(req, encodeHTML, func(data []byte) error {
+		buf.Write(data)
+		return nil
+	})
+}
+
+func (event EventMergeSignals) respond(header http.Header, buf *bytes.Buffer, req *http.Request) error {
+	data, err := event.marshal(req)
+	if err != nil {
+		return err
+	}
+
+	header.Set("Content-Type", "application/json")
+	if event.OnlyIfMissing {
+		header.Set(headerDatastarOnlyIfMissing, "true")
+	}
+
+	buf.Write(data)
+	return nil
+}
+
+func (event EventExecuteScript) respond(header http.Header, buf *bytes.Buffer, req *http.Request) error {
+	if event.err != nil {
+		return event.err
+	}
+
+	attributes, err := event.scriptAttributes(req)
+	if err != nil {
+		return err
+	}
+
+	header.Set("Content-Type", "text/javascript")
+	if len(attributes) > 0 {
+		data, err := json.Marshal(attributes)
+		if err != nil {
+			return fmt.Errorf("marshal script attributes: %w", err)
+		}
+		header.Set(headerDatastarScriptAttributes, string(data))
+	}
+
+	buf.WriteString(strings.Join(event.Script, "\n"))
+	return nil
+}
@@ -0,0 +1,132 @@
+package datastar
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+type testCtxFragment string
+
+func (fragment testCtxFragment) Render(ctx context.Context, w io.Writer) error {
+	_, err := io.WriteString(w, string(fragment))
+	return err
+}
+
+type testFragment string
+
+func (fragment testFragment) Render(w io.Writer) error {
+	_, err := io.WriteString(w, string(fragment))
+	return err
+}
+
+func TestRespondPage(t *testing.T) {
+	page := testCtxFragment("<html><body><div id=\"content\">page</div></body></html>")
+	fragments := MergeFragments(testFragment("<div id=\"content\">fragment</div>"))
+
+	t.Run("plain request", func(t *testing.T) {
+		is := is.New(t)
+
+		resp := httptest.NewRecorder()
+		ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+		defer release()
+
+		is.NoErr(ds.RespondPage(page, fragments))
+		is.Equal(resp.Header().Get("Content-Type"), "text/html; charset=utf-8")
+		is.Equal(resp.Header().Get("Vary"), headerDatastarRequest)
+		is.Equal(resp.Body.String(), string(page))
+	})
+
+	t.Run("datastar request", func(t *testing.T) {
+		is := is.New(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(headerDatastarRequest, "true")
+
+		resp := httptest.NewRecorder()
+		ds, release := New(resp, req)
+		defer release()
+
+		is.True(ds.IsDatastarRequest())
+		is.NoErr(ds.RespondPage(page, fragments))
+		is.Equal(resp.Header().Get("Content-Type"), "text/event-stream")
+		is.Equal(resp.Body.String(), "event: datastar-merge-fragments\ndata: fragments <div id=\"content\">fragment</div>\n\n")
+	})
+}
+
+func TestRespond(t *testing.T) {
+	runTest := func(name string, respond func(ds *Datastar) error, expectedHeaders map[string]string, expectedBody string) {
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+
+			resp := httptest.NewRecorder()
+			ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+			defer release()
+
+			is.NoErr(respond(ds))
+			for name, value := range expectedHeaders {
+				is.Equal(resp.Header().Get(name), value) // header should be expected
+			}
+			is.Equal(resp.Body.String(), expectedBody)
+		})
+	}
+
+	runTest("fragments",
+		func(ds *Datastar) error {
+			return ds.RespondFragments(testFragment("<div id=\"a\">\n</div>"), testFragment("<div id=\"b\"></div>"))
+		},
+		map[string]string{"Content-Type": "text/html; charset=utf-8"},
+		"<div id=\"a\">\n</div><div id=\"b\"></div>",
+	)
+
+	runTest("fragments with options",
+		func(ds *Datastar) error {
+			event := MergeCtxFragments(testCtxFragment("<li>row</li>"))
+			event.Selector = "#rows"
+			event.MergeMode = ModeAppend
+			event.UseViewTransition = true
+			return ds.Respond(event)
+		},
+		map[string]string{
+			headerDatastarSelector:          "#rows",
+			headerDatastarMergeMode:         "append",
+			headerDatastarUseViewTransition: "true",
+		},
+		"<li>row</li>",
+	)
+
+	runTest("signals",
+		func(ds *Datastar) error {
+			return ds.RespondSignals(Signals{"user.name": "john"})
+		},
+		map[string]string{"Content-Type": "application/json"},
+		`{"user":{"name":"john"}}`,
+	)
+
+	runTest("signals only if missing",
+		func(ds *Datastar) error {
+			event := MergeSignalsObj(map[string]int{"count": 1})
+			event.OnlyIfMissing = true
+			return ds.Respond(event)
+		},
+		map[string]string{headerDatastarOnlyIfMissing: "true"},
+		`{"count":1}`,
+	)
+
+	runTest("script",
+		func(ds *Datastar) error {
+			event := ExecuteScript("console.log(1)", "console.log(2)")
+			event.Attributes = map[string]string{"type": "module"}
+			return ds.Respond(event)
+		},
+		map[string]string{
+			"Content-Type":                 "text/javascript",
+			headerDatastarScriptAttributes: `{"type":"module"}`,
+		},
+		"console.log(1)\nconsole.log(2)",
+	)
+}
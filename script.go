@@ -0,0 +1,99 @@
+package datastar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type nonceKey struct{}
+
+// ContextWithNonce returns a context with CSP nonce.
+// Scripts sent with EventExecuteScript get it as a nonce attribute, so pages with `script-src 'nonce-...'` policy run them.
+//
+// Use WithNonceFunc option if nonce is already stored in the context by other middleware.
+func ContextWithNonce(ctx context.Context, nonce string) context.Context {
+	return context.WithValue(ctx, nonceKey{}, nonce)
+}
+
+// NonceFromContext returns CSP nonce stored with ContextWithNonce, or an empty string.
+func NonceFromContext(ctx context.Context) string {
+	nonce, _ := ctx.Value(nonceKey{}).(string)
+	return nonce
+}
+
+// nonce returns CSP nonce for the request.
+func (opts *options) nonce(req *http.Request) string {
+	if opts.nonceFunc != nil {
+		return opts.nonceFunc(req)
+	}
+	return NonceFromContext(req.Context())
+}
+
+// ScriptTemplate formats a script, encoding args as javascript values.
+// Args are encoded with encoding/json, they are safe to place in a script element, quotes and html are escaped.
+// Use %s or %v verbs for args, like:
+//
+//	datastar.ScriptTemplate("showToast(%s, %s)", message, options)
+func ScriptTemplate(format string, args ...any) (string, error) {
+	values := make([]any, 0, len(args))
+	for idx, arg := range args {
+		value, err := jsValue(arg)
+		if err != nil {
+			return "", fmt.Errorf("encode script arg %d: %w", idx, err)
+		}
+		values = append(values, value)
+	}
+	return fmt.Sprintf(format, values...), nil
+}
+
+// ExecuteScriptf is a shortcut to create a `datastar-execute-script` event with a script made by ScriptTemplate.
+// Encoding errors are returned when the event is sent.
+func ExecuteScriptf(format string, args ...any) EventExecuteScript {
+	script, err := ScriptTemplate(format, args...)
+	return EventExecuteScript{
+		Script: []string{script},
+		err:    err,
+	}
+}
+
+// validateAttribute checks that script attribute can be safely sent in an event.
+func validateAttribute(name, value string) error {
+	if name == "" {
+		return fmt.Errorf("script attribute name is empty")
+	}
+	// https://html.spec.whatwg.org/multipage/syntax.html#attributes-2
+	if strings.ContainsFunc(name, func(r rune) bool {
+		return r <= ' ' || r == 0x7f || strings.ContainsRune("\"'>/=", r)
+	}) {
+		return fmt.Errorf("invalid script attribute name %q", name)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("script attribute %s value must be a single line", name)
+	}
+	return nil
+}
+
+// jsString encodes a string as a javascript string literal.
+// It's safe to place in a script element, because json encoder escapes <, >, & and line separators.
+func jsString(s string) string {
+	// strings are always marshalled without errors
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// jsValue encodes a value as a javascript literal with encoding/json, see jsString.
+// Errors are encoded as their message strings.
+func jsValue(v any) (string, error) {
+	if err, ok := v.(error); ok {
+		return jsString(err.Error()), nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,100 @@
+package datastar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestScriptTemplate(t *testing.T) {
+	is := is.New(t)
+
+	script, err := ScriptTemplate("showToast(%s, %v)", "</script>", map[string]int{"timeout": 5})
+	is.NoErr(err)
+	is.Equal(script, `showToast("\u003c/script\u003e", {"timeout":5})`)
+
+	event := ExecuteScriptf("alert(%s)", make(chan int))
+	is.True(event.err != nil) // unsupported value should fail
+}
+
+func TestScriptAttributes(t *testing.T) {
+	send := func(event EventExecuteScript, nonce string, opts ...Option) (string, error) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if nonce != "" {
+			req = req.WithContext(ContextWithNonce(req.Context(), nonce))
+		}
+		resp := httptest.NewRecorder()
+		ds, release := New(resp, req, opts...)
+		defer release()
+
+		err := ds.Send(event)
+		return resp.Body.String(), err
+	}
+
+	t.Run("sorted with nonce", func(t *testing.T) {
+		is := is.New(t)
+
+		event := ExecuteScript("run()")
+		event.Attributes = map[string]string{"type": "module", "defer": "true", "async": "true"}
+		body, err := send(event, "abc")
+		is.NoErr(err)
+		is.Equal(body, "event: datastar-execute-script\n"+
+			"data: attributes async true\n"+
+			"data: attributes defer true\n"+
+			"data: attributes nonce abc\n"+
+			"data: attributes type module\n"+
+			"data: script run()\n\n")
+		is.Equal(len(event.Attributes), 3) // event attributes should not be modified
+	})
+
+	t.Run("explicit nonce", func(t *testing.T) {
+		is := is.New(t)
+
+		event := ExecuteScript("run()")
+		event.Attributes = map[string]string{"nonce": "explicit"}
+		body, err := send(event, "abc")
+		is.NoErr(err)
+		is.Equal(body, "event: datastar-execute-script\ndata: attributes nonce explicit\ndata: script run()\n\n")
+	})
+
+	t.Run("nonce func", func(t *testing.T) {
+		is := is.New(t)
+
+		nonce := WithNonceFunc(func(r *http.Request) string { return "fromfunc" })
+		body, err := send(ExecuteScript("run()"), "", nonce)
+		is.NoErr(err)
+		is.Equal(body, "event: datastar-execute-script\ndata: attributes nonce fromfunc\ndata: script run()\n\n")
+	})
+
+	t.Run("invalid attributes", func(t *testing.T) {
+		is := is.New(t)
+
+		for name, value := range map[string]string{
+			"":        "x",
+			"on load": "x",
+			"a=b":     "x",
+			`"x`:      "x",
+			"type":    "module\nscript alert(1)",
+		} {
+			event := ExecuteScript("run()")
+			event.Attributes = map[string]string{name: value}
+			_, err := send(event, "")
+			is.True(err != nil) // invalid attribute should fail
+		}
+	})
+
+	t.Run("respond", func(t *testing.T) {
+		is := is.New(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(ContextWithNonce(req.Context(), "abc"))
+		resp := httptest.NewRecorder()
+		ds, release := New(resp, req)
+		defer release()
+
+		is.NoErr(ds.Respond(ExecuteScript("run()")))
+		is.Equal(resp.Header().Get(headerDatastarScriptAttributes), `{"nonce":"abc"}`)
+	})
+}
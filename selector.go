@@ -0,0 +1,151 @@
+package datastar
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidSelector is returned when an event has a malformed css selector.
+var ErrInvalidSelector = fmt.Errorf("invalid css selector")
+
+// ByID returns a css selector matching an element by id, like "#user-1".
+// Id is escaped, so any value is safe to use, empty id makes a selector that fails validation.
+func ByID(id string) string {
+	return "#" + cssIdent(id)
+}
+
+// ByClass returns a css selector matching elements by class, like ".row".
+// Class is escaped, so any value is safe to use, empty class makes a selector that fails validation.
+func ByClass(class string) string {
+	return "." + cssIdent(class)
+}
+
+// Attr returns a css selector matching elements by attribute value, like `[data-id="42"]`.
+// Both name and value are escaped, so any value is safe to use.
+// Selectors can be combined by concatenation, like ByClass("row") + Attr("data-id", id).
+func Attr(name, value string) string {
+	return "[" + cssIdent(name) + "=" + cssString(value) + "]"
+}
+
+// ValidateSelector checks that selector is not empty, fits in a single line,
+// has closed quotes, balanced brackets and no empty ids or classes.
+// It doesn't parse css, so browser may still reject a selector that passes validation.
+func ValidateSelector(selector string) error {
+	if strings.TrimSpace(selector) == "" {
+		return fmt.Errorf("%w: selector is empty", ErrInvalidSelector)
+	}
+	if strings.ContainsAny(selector, "\r\n\x00") {
+		return fmt.Errorf("%w: selector %q must be a single line", ErrInvalidSelector, selector)
+	}
+
+	var (
+		brackets []rune
+		quote    rune
+		escaped  bool
+	)
+	for idx, r := range selector {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case (r == '#' || r == '.') && (len(brackets) == 0 || brackets[len(brackets)-1] != '['):
+			if !startsIdent(selector[idx+1:]) {
+				return fmt.Errorf("%w: empty id or class in selector %q", ErrInvalidSelector, selector)
+			}
+		case r == '[' || r == '(':
+			brackets = append(brackets, r)
+		case r == ']' || r == ')':
+			open := '['
+			if r == ')' {
+				open = '('
+			}
+			if len(brackets) == 0 || brackets[len(brackets)-1] != open {
+				return fmt.Errorf("%w: unexpected %q in selector %q", ErrInvalidSelector, r, selector)
+			}
+			brackets = brackets[:len(brackets)-1]
+		}
+	}
+
+	switch {
+	case escaped:
+		return fmt.Errorf("%w: selector %q ends with an escape", ErrInvalidSelector, selector)
+	case quote != 0:
+		return fmt.Errorf("%w: unclosed quote in selector %q", ErrInvalidSelector, selector)
+	case len(brackets) > 0:
+		return fmt.Errorf("%w: unclosed %q in selector %q", ErrInvalidSelector, brackets[len(brackets)-1], selector)
+	}
+	return nil
+}
+
+// cssIdent escapes a css identifier.
+// See https://drafts.csswg.org/cssom/#serialize-an-identifier
+func cssIdent(value string) string {
+	if value == "-" {
+		return `\-`
+	}
+
+	var sb strings.Builder
+	for idx, r := range value {
+		switch {
+		case r == 0 || r == utf8.RuneError:
+			sb.WriteRune(utf8.RuneError)
+		case r < 0x20 || r == 0x7f,
+			idx == 0 && isDigit(r),
+			idx == 1 && isDigit(r) && value[0] == '-':
+			fmt.Fprintf(&sb, `\%x `, r)
+		case r >= 0x80 || r == '-' || r == '_' || isDigit(r) || isLetter(r):
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// cssString escapes a double quoted css string.
+// See https://drafts.csswg.org/cssom/#serialize-a-string
+func cssString(value string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range value {
+		switch {
+		case r == 0 || r == utf8.RuneError:
+			sb.WriteRune(utf8.RuneError)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&sb, `\%x `, r)
+		case r == '"' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// startsIdent reports whether s starts with a css identifier, possibly escaped.
+func startsIdent(s string) bool {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return false
+	}
+	return r >= 0x80 || r == '-' || r == '_' || r == '\\' || isDigit(r) || isLetter(r)
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
@@ -0,0 +1,93 @@
+package datastar
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestSelectorBuilder(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(ByID("user-1"), "#user-1")
+	is.Equal(ByID("1user"), `#\31 user`)
+	is.Equal(ByID("-1"), `#-\31 `)
+	is.Equal(ByID("-"), `#\-`)
+	is.Equal(ByID("a.b:c"), `#a\.b\:c`)
+	is.Equal(ByClass("row active"), `.row\ active`)
+	is.Equal(Attr("data-id", `4"2\`), `[data-id="4\"2\\"]`)
+	is.Equal(Attr("data-id", "a\nb"), `[data-id="a\a b"]`)
+
+	for _, selector := range []string{
+		ByID("a]b"), ByClass(`"`), Attr("x", `"]`), Attr("a]", "\n"),
+		"#rows > .row:not(.hidden)", `[title='a"]']`,
+	} {
+		is.NoErr(ValidateSelector(selector)) // selector should be valid
+	}
+}
+
+func TestValidateSelector(t *testing.T) {
+	is := is.New(t)
+
+	for _, selector := range []string{
+		"", "  ", "#a\n#b", "[data-id", "#a]", ":not(.a", `[x="a]`, `#a\`, "[x)",
+		ByID(""), ByClass(""), ".a .", "div# > a", ":not(.)",
+	} {
+		err := ValidateSelector(selector)
+		is.True(errors.Is(err, ErrInvalidSelector)) // selector should be invalid
+	}
+}
+
+func TestRemoveFragments(t *testing.T) {
+	send := func(event Event) (string, error) {
+		resp := httptest.NewRecorder()
+		ds, release := New(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+		defer release()
+
+		err := ds.Send(event)
+		return resp.Body.String(), err
+	}
+
+	t.Run("multiple selectors", func(t *testing.T) {
+		is := is.New(t)
+
+		event := RemoveFragments(ByID("a"), ByClass("b"))
+		event.UseViewTransition = true
+		body, err := send(event)
+		is.NoErr(err)
+		is.Equal(body, "event: datastar-remove-fragments\ndata: selector #a, .b\ndata: useViewTransition true\n\n")
+	})
+
+	t.Run("selector and selectors", func(t *testing.T) {
+		is := is.New(t)
+
+		body, err := send(EventRemoveFragments{Selector: "#a"})
+		is.NoErr(err)
+		is.Equal(body, "event: datastar-remove-fragments\ndata: selector #a\n\n")
+
+		body, err = send(EventRemoveFragments{Selector: "#a", Selectors: []string{".b"}})
+		is.NoErr(err)
+		is.Equal(body, "event: datastar-remove-fragments\ndata: selector #a, .b\n\n")
+	})
+
+	t.Run("invalid selector", func(t *testing.T) {
+		is := is.New(t)
+
+		_, err := send(RemoveFragments("#a", "[b"))
+		is.True(errors.Is(err, ErrInvalidSelector))
+
+		_, err = send(RemoveFragments())
+		is.True(errors.Is(err, ErrInvalidSelector)) // no selectors should fail
+
+		_, err = send(RemoveFragments(ByID("")))
+		is.True(errors.Is(err, ErrInvalidSelector)) // empty id should fail
+
+		event := MergeFragments()
+		event.Selector = "#a\ndata: script alert(1)"
+		_, err = send(event)
+		is.True(errors.Is(err, ErrInvalidSelector)) // merge selector should be validated
+	})
+}
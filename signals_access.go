@@ -0,0 +1,155 @@
+package datastar
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/valyala/fastjson"
+)
+
+// ErrSignalNotFound is returned when there is no signal at requested path.
+var ErrSignalNotFound = fmt.Errorf("signal not found")
+
+// Typed signal accessors read values directly from parsed signals, without unmarshalling them.
+// They are cheaper than UnmarshalSignals with a path, when only a few values are needed.
+// Accessors work only with json signals, use UnmarshalSignals for form submissions.
+//
+// Path can be separated by "." or be made of individual components, like: "my.data.value" or ("my", "data", "value").
+
+// SignalString returns a string signal at path.
+func (signals *RequestSignals) SignalString(path ...string) (string, error) {
+	value, err := signals.lookupSignal(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := value.StringBytes()
+	if err != nil {
+		return "", signalTypeError(path, err)
+	}
+	return string(data), nil
+}
+
+// SignalInt returns an integer signal at path.
+func (signals *RequestSignals) SignalInt(path ...string) (int, error) {
+	value, err := signals.lookupSignal(path)
+	if err != nil {
+		return 0, err
+	}
+	i, err := value.Int()
+	if err != nil {
+		return 0, signalTypeError(path, err)
+	}
+	return i, nil
+}
+
+// SignalFloat returns a number signal at path.
+func (signals *RequestSignals) SignalFloat(path ...string) (float64, error) {
+	value, err := signals.lookupSignal(path)
+	if err != nil {
+		return 0, err
+	}
+	f, err := value.Float64()
+	if err != nil {
+		return 0, signalTypeError(path, err)
+	}
+	return f, nil
+}
+
+// SignalBool returns a boolean signal at path.
+func (signals *RequestSignals) SignalBool(path ...string) (bool, error) {
+	value, err := signals.lookupSignal(path)
+	if err != nil {
+		return false, err
+	}
+	b, err := value.Bool()
+	if err != nil {
+		return false, signalTypeError(path, err)
+	}
+	return b, nil
+}
+
+// SignalExists reports whether there is a signal at path.
+// It returns false if signals can't be read.
+func (signals *RequestSignals) SignalExists(path ...string) bool {
+	_, err := signals.lookupSignal(path)
+	return err == nil
+}
+
+// SignalKeys returns keys of an object signal at path, in order they were sent.
+// Empty path returns top level signal names.
+func (signals *RequestSignals) SignalKeys(path ...string) ([]string, error) {
+	value, err := signals.lookupSignal(path)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := value.Object()
+	if err != nil {
+		return nil, signalTypeError(path, err)
+	}
+
+	keys := make([]string, 0, obj.Len())
+	obj.Visit(func(key []byte, _ *fastjson.Value) {
+		keys = append(keys, string(key))
+	})
+	return keys, nil
+}
+
+// SignalArray returns an iterator over elements of an array signal at path.
+// Elements are parsed fastjson values, they are valid until signals are released:
+// when Datastar release func is called or SignalsMiddleware handler returns.
+func (signals *RequestSignals) SignalArray(path ...string) (iter.Seq2[int, *fastjson.Value], error) {
+	value, err := signals.lookupSignal(path)
+	if err != nil {
+		return nil, err
+	}
+	array, err := value.Array()
+	if err != nil {
+		return nil, signalTypeError(path, err)
+	}
+
+	return func(yield func(int, *fastjson.Value) bool) {
+		for idx, elem := range array {
+			if !yield(idx, elem) {
+				return
+			}
+		}
+	}, nil
+}
+
+// lookupSignal finds a parsed json signal value at path.
+// Empty path returns the signals object itself.
+func (signals *RequestSignals) lookupSignal(path []string) (*fastjson.Value, error) {
+	if err := signals.readRawData(); err != nil {
+		return nil, err
+	}
+
+	if err := signals.filterSignals(); err != nil {
+		return nil, err
+	}
+
+	if signals.form != nil {
+		return nil, fmt.Errorf("%w: form signals can only be read with UnmarshalSignals", ErrContentType)
+	}
+
+	if err := signals.parseSignals(); err != nil {
+		return nil, err
+	}
+
+	if len(path) == 0 {
+		return signals.jsonData, nil
+	}
+
+	fullpath := strings.Join(path, signalSeparator)
+	keys := strings.Split(fullpath, signalSeparator)
+
+	value := signals.jsonData.Get(keys...)
+	if value == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSignalNotFound, fullpath)
+	}
+	return value, nil
+}
+
+func signalTypeError(path []string, err error) error {
+	return fmt.Errorf("signal %s: %w", strings.Join(path, signalSeparator), err)
+}
@@ -0,0 +1,78 @@
+package datastar
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+const testAccessSignals = `{"user": {"name": "john", "age": 42, "score": 1.5, "admin": true}, "tags": ["a", "b", "c"]}`
+
+func TestSignalAccessors(t *testing.T) {
+	is := is.New(t)
+
+	ds, release := New(httptest.NewRecorder(), newSignalsRequest(testAccessSignals))
+	defer release()
+
+	name, err := ds.SignalString("user.name")
+	is.NoErr(err)
+	is.Equal(name, "john")
+
+	age, err := ds.SignalInt("user", "age")
+	is.NoErr(err)
+	is.Equal(age, 42)
+
+	score, err := ds.SignalFloat("user.score")
+	is.NoErr(err)
+	is.Equal(score, 1.5)
+
+	admin, err := ds.SignalBool("user.admin")
+	is.NoErr(err)
+	is.True(admin)
+
+	is.True(ds.SignalExists("user.name"))
+	is.True(!ds.SignalExists("user.email"))
+
+	keys, err := ds.SignalKeys("user")
+	is.NoErr(err)
+	is.Equal(keys, []string{"name", "age", "score", "admin"})
+
+	tags, err := ds.SignalArray("tags")
+	is.NoErr(err)
+	var values []string
+	for _, tag := range tags {
+		values = append(values, string(tag.GetStringBytes()))
+	}
+	is.Equal(values, []string{"a", "b", "c"})
+
+	_, err = ds.SignalString("user.email")
+	is.True(errors.Is(err, ErrSignalNotFound)) // missing signal should fail
+
+	_, err = ds.SignalInt("user.name")
+	is.True(err != nil) // wrong type should fail
+}
+
+func BenchmarkSignalString(b *testing.B) {
+	ds, release := New(httptest.NewRecorder(), newSignalsRequest(testAccessSignals))
+	defer release()
+
+	for b.Loop() {
+		if _, err := ds.SignalString("user.name"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalSignalsPath(b *testing.B) {
+	ds, release := New(httptest.NewRecorder(), newSignalsRequest(testAccessSignals))
+	defer release()
+
+	for b.Loop() {
+		var name string
+		if err := ds.UnmarshalSignals(&name, "user.name"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
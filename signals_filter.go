@@ -0,0 +1,168 @@
+package datastar
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/valyala/fastjson"
+)
+
+// LocalSignalPrefix marks a signal as local.
+// Datastar doesn't send local signals to the server unless `IncludeLocal` option is set in the action.
+const LocalSignalPrefix = "_"
+
+// ErrLocalSignal is returned when request contains local signals and LocalSignalsReject is used.
+var ErrLocalSignal = fmt.Errorf("local signals are not allowed")
+
+// ErrSignalNotAllowed is returned when request contains a signal that is not in the list of allowed signals.
+var ErrSignalNotAllowed = fmt.Errorf("signal is not allowed")
+
+// LocalSignalsMode sets how local signals (with names starting with "_") are handled when reading request signals.
+type LocalSignalsMode int
+
+const (
+	LocalSignalsKeep   LocalSignalsMode = iota //Local signals are decoded as any other signal. This is the default.
+	LocalSignalsStrip                          //Local signals are removed from request signals before decoding.
+	LocalSignalsReject                         //Requests with local signals are rejected with ErrLocalSignal.
+)
+
+// LocalSignal returns a name for a local signal.
+// Only the last path component is prefixed: "form.dirty" or ("form", "dirty") become "form._dirty".
+//
+// Use it to build Signals that should stay on the client.
+func LocalSignal(path ...string) string {
+	fullpath := strings.Join(path, signalSeparator)
+	idx := strings.LastIndex(fullpath, signalSeparator)
+	name := fullpath[idx+1:]
+	if isLocalName(name) {
+		return fullpath
+	}
+	return fullpath[:idx+1] + LocalSignalPrefix + name
+}
+
+// IsLocalSignal reports whether a signal path is local, meaning any of it's components starts with "_".
+func IsLocalSignal(path string) bool {
+	for name := range strings.SplitSeq(path, signalSeparator) {
+		if isLocalName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLocalName(name string) bool {
+	return strings.HasPrefix(name, LocalSignalPrefix)
+}
+
+type signalMatch int
+
+const (
+	signalDenied  signalMatch = iota // path is not allowed
+	signalAllowed                    // path and all of it's children are allowed
+	signalParent                     // path is a parent object of an allowed path
+)
+
+// signalFilter checks request signals against local signals mode and allowed signal paths.
+type signalFilter struct {
+	local   LocalSignalsMode
+	allowed [][]string
+}
+
+func (filter signalFilter) enabled() bool {
+	return filter.local != LocalSignalsKeep || len(filter.allowed) > 0
+}
+
+func (filter signalFilter) match(path []string) signalMatch {
+	if len(filter.allowed) == 0 {
+		return signalAllowed
+	}
+
+	match := signalDenied
+	for _, allowed := range filter.allowed {
+		if len(allowed) <= len(path) && slices.Equal(allowed, path[:len(allowed)]) {
+			return signalAllowed
+		}
+		if len(allowed) > len(path) && slices.Equal(allowed[:len(path)], path) {
+			match = signalParent
+		}
+	}
+	return match
+}
+
+// filterObject walks the signals object, removing or rejecting signals according to the filter.
+// Returns true if the object was changed.
+func (filter signalFilter) filterObject(obj *fastjson.Object, path []string, allowed bool) (changed bool, err error) {
+	keys := make([]string, 0, obj.Len())
+	obj.Visit(func(key []byte, _ *fastjson.Value) {
+		keys = append(keys, string(key))
+	})
+
+	for _, key := range keys {
+		keyPath := append(path[:len(path):len(path)], key)
+
+		if isLocalName(key) {
+			switch filter.local {
+			case LocalSignalsReject:
+				return false, fmt.Errorf("%w: %s", ErrLocalSignal, strings.Join(keyPath, signalSeparator))
+			case LocalSignalsStrip:
+				obj.Del(key)
+				changed = true
+				continue
+			}
+		}
+
+		value := obj.Get(key)
+		keyAllowed := allowed
+		if !keyAllowed {
+			switch filter.match(keyPath) {
+			case signalAllowed:
+				keyAllowed = true
+			case signalParent:
+				if value.Type() != fastjson.TypeObject {
+					return false, fmt.Errorf("%w: %s", ErrSignalNotAllowed, strings.Join(keyPath, signalSeparator))
+				}
+			default:
+				return false, fmt.Errorf("%w: %s", ErrSignalNotAllowed, strings.Join(keyPath, signalSeparator))
+			}
+		}
+
+		if keyAllowed && filter.local == LocalSignalsKeep {
+			// nothing left to check in children
+			continue
+		}
+
+		if value.Type() == fastjson.TypeObject {
+			childChanged, err := filter.filterObject(value.GetObject(), keyPath, keyAllowed)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || childChanged
+		}
+	}
+	return changed, nil
+}
+
+// filterForm removes or rejects form values according to the filter.
+// Form field names are treated as "." separated signal paths.
+func (filter signalFilter) filterForm(values map[string][]string) error {
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		path := strings.Split(name, signalSeparator)
+
+		if slices.ContainsFunc(path, isLocalName) {
+			switch filter.local {
+			case LocalSignalsReject:
+				return fmt.Errorf("%w: %s", ErrLocalSignal, name)
+			case LocalSignalsStrip:
+				delete(values, name)
+				continue
+			}
+		}
+
+		if filter.match(path) != signalAllowed {
+			return fmt.Errorf("%w: %s", ErrSignalNotAllowed, name)
+		}
+	}
+	return nil
+}
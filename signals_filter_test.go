@@ -0,0 +1,100 @@
+package datastar
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func newSignalsRequest(body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+}
+
+func TestFilterSignals(t *testing.T) {
+	runTest := func(name string, opts []Option, body string, expected map[string]any, expectedErr error) {
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+
+			ds, release := New(httptest.NewRecorder(), newSignalsRequest(body), opts...)
+			defer release()
+
+			var signals map[string]any
+			err := ds.UnmarshalSignals(&signals)
+			is.True(errors.Is(err, expectedErr)) // error should be expected
+			if expectedErr != nil {
+				return
+			}
+
+			is.Equal(signals, expected) // decoded signals should be equal to expected
+		})
+	}
+
+	runTest("ok: keep local",
+		nil,
+		`{"name": "john", "_dirty": true}`,
+		map[string]any{"name": "john", "_dirty": true},
+		nil,
+	)
+
+	runTest("ok: strip local",
+		[]Option{WithLocalSignals(LocalSignalsStrip)},
+		`{"name": "john", "_dirty": true, "form": {"_open": true, "value": 1}}`,
+		map[string]any{"name": "john", "form": map[string]any{"value": 1.0}},
+		nil,
+	)
+
+	runTest("fail: reject nested local",
+		[]Option{WithLocalSignals(LocalSignalsReject)},
+		`{"name": "john", "form": {"_open": true}}`,
+		nil,
+		ErrLocalSignal,
+	)
+
+	runTest("ok: allowed paths",
+		[]Option{WithAllowedSignals("user.name", "filter")},
+		`{"user": {"name": "john"}, "filter": {"q": "x", "page": 2}}`,
+		map[string]any{
+			"user":   map[string]any{"name": "john"},
+			"filter": map[string]any{"q": "x", "page": 2.0},
+		},
+		nil,
+	)
+
+	runTest("fail: signal not in allowed paths",
+		[]Option{WithAllowedSignals("user.name")},
+		`{"user": {"name": "john", "admin": true}}`,
+		nil,
+		ErrSignalNotAllowed,
+	)
+
+	runTest("fail: value in place of allowed parent",
+		[]Option{WithAllowedSignals("user.name")},
+		`{"user": "john"}`,
+		nil,
+		ErrSignalNotAllowed,
+	)
+
+	runTest("ok: strip local before allowed check",
+		[]Option{WithLocalSignals(LocalSignalsStrip), WithAllowedSignals("name")},
+		`{"name": "john", "_dirty": true}`,
+		map[string]any{"name": "john"},
+		nil,
+	)
+}
+
+func TestLocalSignal(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(LocalSignal("dirty"), "_dirty")
+	is.Equal(LocalSignal("form.dirty"), "form._dirty")
+	is.Equal(LocalSignal("form", "dirty"), "form._dirty")
+	is.Equal(LocalSignal("form._dirty"), "form._dirty")
+
+	is.True(IsLocalSignal("form._dirty"))
+	is.True(IsLocalSignal("_form.dirty"))
+	is.True(!IsLocalSignal("form.dirty"))
+}
@@ -0,0 +1,292 @@
+package datastar
+
+import (
+	"encoding"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Form signals are decoded with following rules:
+//
+// Struct fields are matched with names from `form` tag, `json` tag or field name, in that order.
+// Nested structs and maps use "." separated names, like "user.name".
+// Basic types (strings, bools, numbers) and encoding.TextUnmarshaler are parsed from the first value.
+// Slices are filled with all values of a field.
+// Maps with string keys are filled with all fields under a name.
+// Values of type any are set to string, []string (for multiple values) or map[string]any (for nested names).
+// Checkbox value "on" is decoded as true.
+// Empty values are skipped, leaving target untouched.
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// decodeForm decodes form values at path (may be empty) into target, target must be a non-nil pointer.
+func decodeForm(values map[string][]string, path string, target any) (found bool, err error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return false, fmt.Errorf("decode form: target must be a non-nil pointer, got %T", target)
+	}
+	return decodeFormValue(values, path, v.Elem())
+}
+
+// hasFormKey checks if form has key itself or any nested keys.
+func hasFormKey(values map[string][]string, key string) bool {
+	if key == "" {
+		return len(values) > 0
+	}
+	if _, ok := values[key]; ok {
+		return true
+	}
+	prefix := key + signalSeparator
+	for name := range values {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// nestedFormKeys returns names of direct children of key.
+func nestedFormKeys(values map[string][]string, key string) []string {
+	prefix := ""
+	if key != "" {
+		prefix = key + signalSeparator
+	}
+
+	children := make(map[string]struct{})
+	for name := range values {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		child, _, _ := strings.Cut(rest, signalSeparator)
+		children[child] = struct{}{}
+	}
+	return slices.Sorted(maps.Keys(children))
+}
+
+func decodeFormValue(values map[string][]string, key string, v reflect.Value) (bool, error) {
+	if !hasFormKey(values, key) {
+		return false, nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeFormValue(values, key, v.Elem())
+	}
+
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		value := firstFormValue(values, key)
+		if value == "" {
+			return false, nil
+		}
+		unmarshaler := v.Addr().Interface().(encoding.TextUnmarshaler)
+		if err := unmarshaler.UnmarshalText([]byte(value)); err != nil {
+			return false, fmt.Errorf("decode form field %s: %w", key, err)
+		}
+		return true, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return decodeFormStruct(values, key, v)
+	case reflect.Map:
+		return decodeFormMap(values, key, v)
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return false, fmt.Errorf("decode form field %s: unsupported type %s", key, v.Type())
+		}
+		v.Set(reflect.ValueOf(formAny(values, key)))
+		return true, nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// []byte is a single value
+			break
+		}
+		fieldValues := values[key]
+		if len(fieldValues) == 0 {
+			return false, nil
+		}
+		slice := reflect.MakeSlice(v.Type(), len(fieldValues), len(fieldValues))
+		for idx, value := range fieldValues {
+			if err := setFormScalar(slice.Index(idx), key, value); err != nil {
+				return false, err
+			}
+		}
+		v.Set(slice)
+		return true, nil
+	}
+
+	value := firstFormValue(values, key)
+	if value == "" {
+		return false, nil
+	}
+	return true, setFormScalar(v, key, value)
+}
+
+func decodeFormStruct(values map[string][]string, key string, v reflect.Value) (bool, error) {
+	found := false
+	t := v.Type()
+	for idx := range t.NumField() {
+		field := t.Field(idx)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, ok := formFieldName(field)
+		if !ok {
+			continue
+		}
+
+		fieldKey := addName(key, name)
+		if field.Anonymous && name == "" {
+			// embedded struct without a name, its fields are promoted
+			fieldKey = key
+		}
+
+		fieldFound, err := decodeFormValue(values, fieldKey, v.Field(idx))
+		if err != nil {
+			return false, err
+		}
+		found = found || fieldFound
+	}
+	return found, nil
+}
+
+func formFieldName(field reflect.StructField) (name string, ok bool) {
+	for _, tagName := range []string{"form", "json"} {
+		tag, ok := field.Tag.Lookup(tagName)
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(tag, ",")
+		if name == "-" {
+			return "", false
+		}
+		if name != "" {
+			return name, true
+		}
+	}
+
+	if field.Anonymous && field.Type.Kind() == reflect.Struct {
+		return "", true
+	}
+	return field.Name, true
+}
+
+func decodeFormMap(values map[string][]string, key string, v reflect.Value) (bool, error) {
+	t := v.Type()
+	if t.Key().Kind() != reflect.String {
+		return false, fmt.Errorf("decode form field %s: unsupported map key type %s", key, t.Key())
+	}
+
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+
+	found := false
+	for _, child := range nestedFormKeys(values, key) {
+		elem := reflect.New(t.Elem()).Elem()
+		childFound, err := decodeFormValue(values, addName(key, child), elem)
+		if err != nil {
+			return false, err
+		}
+		if childFound {
+			v.SetMapIndex(reflect.ValueOf(child).Convert(t.Key()), elem)
+			found = true
+		}
+	}
+	return found, nil
+}
+
+// formAny converts values at key to a json-like value.
+func formAny(values map[string][]string, key string) any {
+	if fieldValues, ok := values[key]; ok {
+		if len(fieldValues) == 1 {
+			return fieldValues[0]
+		}
+		return slices.Clone(fieldValues)
+	}
+
+	obj := make(map[string]any)
+	for _, child := range nestedFormKeys(values, key) {
+		obj[child] = formAny(values, addName(key, child))
+	}
+	return obj
+}
+
+func firstFormValue(values map[string][]string, key string) string {
+	if fieldValues := values[key]; len(fieldValues) > 0 {
+		return fieldValues[0]
+	}
+	return ""
+}
+
+func setFormScalar(v reflect.Value, key string, value string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		unmarshaler := v.Addr().Interface().(encoding.TextUnmarshaler)
+		if err := unmarshaler.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("decode form field %s: %w", key, err)
+		}
+		return nil
+	}
+
+	var err error
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		var b bool
+		b, err = parseFormBool(value)
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		i, err = strconv.ParseInt(value, 10, v.Type().Bits())
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		u, err = strconv.ParseUint(value, 10, v.Type().Bits())
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(value, v.Type().Bits())
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("decode form field %s: unsupported type %s", key, v.Type())
+		}
+		v.SetBytes([]byte(value))
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return fmt.Errorf("decode form field %s: unsupported type %s", key, v.Type())
+		}
+		v.Set(reflect.ValueOf(value))
+	default:
+		return fmt.Errorf("decode form field %s: unsupported type %s", key, v.Type())
+	}
+
+	if err != nil {
+		return fmt.Errorf("decode form field %s: %w", key, err)
+	}
+	return nil
+}
+
+func parseFormBool(value string) (bool, error) {
+	if value == "on" {
+		// checkbox without a value
+		return true, nil
+	}
+	return strconv.ParseBool(value)
+}
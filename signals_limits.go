@@ -0,0 +1,128 @@
+package datastar
+
+import (
+	"fmt"
+	"io"
+	"mime"
+)
+
+const (
+	// DefaultMaxSignalsSize is the default limit of signals payload size in bytes, both for query and body.
+	DefaultMaxSignalsSize int64 = 1 << 20
+
+	// DefaultMaxSignalsDepth is the default limit of nested objects and arrays in signals.
+	DefaultMaxSignalsDepth = 64
+)
+
+// ErrSignalsTooLarge is returned when signals payload exceeds the configured maximum size.
+var ErrSignalsTooLarge = fmt.Errorf("signals payload is too large")
+
+// ErrSignalsTooDeep is returned when signals contain objects or arrays nested deeper than the configured maximum depth.
+var ErrSignalsTooDeep = fmt.Errorf("signals are nested too deep")
+
+// ErrContentType is returned when request body has a content type that can't be decoded as signals.
+var ErrContentType = fmt.Errorf("unsupported signals content type")
+
+const (
+	contentTypeJSON      = "application/json"
+	contentTypeForm      = "application/x-www-form-urlencoded"
+	contentTypeMultipart = "multipart/form-data"
+)
+
+// limitValue returns the limit to use: zero means default, negative means no limit.
+func limitValue[T int | int64](value, defaultValue T) T {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// signalsMediaType parses request content type.
+// Datastar sends json signals with "application/json", but empty content type is treated as json too.
+func signalsMediaType(contentType string) (string, error) {
+	if contentType == "" {
+		return contentTypeJSON, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrContentType, err)
+	}
+	return mediaType, nil
+}
+
+// readLimited reads all data from reader, failing with ErrSignalsTooLarge if it has more than limit bytes.
+// Negative limit disables the check.
+// Data read so far is returned along with errors, so it can be restored.
+func readLimited(reader io.Reader, limit int64) ([]byte, error) {
+	if limit < 0 {
+		return io.ReadAll(reader)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return data, err
+	}
+	if int64(len(data)) > limit {
+		return data, fmt.Errorf("%w: limit is %d bytes", ErrSignalsTooLarge, limit)
+	}
+	return data, nil
+}
+
+// formSize returns the size of form values in bytes.
+func formSize(values map[string][]string) int64 {
+	size := int64(0)
+	for _, fieldValues := range values {
+		for _, value := range fieldValues {
+			size += int64(len(value))
+		}
+	}
+	return size
+}
+
+// checkSize fails with ErrSignalsTooLarge if size is over the limit.
+// Negative limit disables the check.
+func checkSize(size int64, limit int64) error {
+	if limit >= 0 && size > limit {
+		return fmt.Errorf("%w: %d bytes, limit is %d bytes", ErrSignalsTooLarge, size, limit)
+	}
+	return nil
+}
+
+// checkDepth scans json data and fails with ErrSignalsTooDeep if objects or arrays are nested deeper than the limit.
+// It doesn't validate json, that is left to the parser.
+// Negative limit disables the check.
+func checkDepth(data []byte, limit int) error {
+	if limit < 0 {
+		return nil
+	}
+
+	depth := 0
+	inString, escaped := false, false
+	for _, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > limit {
+				return fmt.Errorf("%w: limit is %d", ErrSignalsTooDeep, limit)
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package datastar
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestSignalsLimits(t *testing.T) {
+	runTest := func(name string, req *http.Request, opts []Option, expectedErr error) {
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+
+			ds, release := New(httptest.NewRecorder(), req, opts...)
+			defer release()
+
+			var signals map[string]any
+			err := ds.UnmarshalSignals(&signals)
+			is.True(errors.Is(err, expectedErr)) // error should be expected
+
+			// errors are final, body is not read again
+			err = ds.UnmarshalSignals(&signals)
+			is.True(errors.Is(err, expectedErr)) // repeated error should be expected
+		})
+	}
+
+	largeBody := `{"data": "` + strings.Repeat("x", 100) + `"}`
+	deepBody := strings.Repeat(`{"a":`, 10) + "1" + strings.Repeat("}", 10)
+
+	runTest("ok: body under limit",
+		newSignalsRequest(largeBody),
+		[]Option{WithMaxSignalsSize(1000)},
+		nil,
+	)
+
+	runTest("fail: body over limit",
+		newSignalsRequest(largeBody),
+		[]Option{WithMaxSignalsSize(50)},
+		ErrSignalsTooLarge,
+	)
+
+	runTest("fail: body over limit with unknown length",
+		func() *http.Request {
+			req := newSignalsRequest(largeBody)
+			req.ContentLength = -1
+			return req
+		}(),
+		[]Option{WithMaxSignalsSize(50)},
+		ErrSignalsTooLarge,
+	)
+
+	runTest("ok: body limit disabled",
+		newSignalsRequest(largeBody),
+		[]Option{WithMaxSignalsSize(-1)},
+		nil,
+	)
+
+	runTest("fail: query over limit",
+		httptest.NewRequest(http.MethodGet, "/?datastar="+url.QueryEscape(largeBody), nil),
+		[]Option{WithMaxSignalsSize(50)},
+		ErrSignalsTooLarge,
+	)
+
+	runTest("fail: nested too deep",
+		newSignalsRequest(deepBody),
+		[]Option{WithMaxSignalsDepth(5)},
+		ErrSignalsTooDeep,
+	)
+
+	runTest("ok: brackets in strings are not counted",
+		newSignalsRequest(`{"a": "{{{{{{[[[[[[\"{{{{"}`),
+		[]Option{WithMaxSignalsDepth(2)},
+		nil,
+	)
+
+	runTest("fail: unknown content type",
+		func() *http.Request {
+			req := newSignalsRequest(`{}`)
+			req.Header.Set("Content-Type", "text/plain")
+			return req
+		}(),
+		nil,
+		ErrContentType,
+	)
+
+	runTest("ok: json content type with charset",
+		func() *http.Request {
+			req := newSignalsRequest(`{}`)
+			req.Header.Set("Content-Type", "application/json; charset=utf-8")
+			return req
+		}(),
+		nil,
+		nil,
+	)
+}
@@ -0,0 +1,82 @@
+package datastar
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// sendToStalledClient sends large events to a client that never reads the response, until Send fails.
+func sendToStalledClient(t *testing.T, opts ...Option) (errs []error) {
+	t.Helper()
+
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+
+		ds, release := New(w, r, opts...)
+		defer release()
+
+		fragment := HTML(strings.Repeat("x", 1<<20))
+		for range 500 {
+			err := ds.Send(MergeFragments(fragment))
+			if err != nil {
+				errs = append(errs, err)
+				if len(errs) == 2 {
+					return
+				}
+			}
+		}
+	}))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: %s\r\n\r\n", server.Listener.Addr())
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("send to stalled client should fail")
+	}
+	return errs
+}
+
+func TestWriteTimeout(t *testing.T) {
+	t.Run("slow client", func(t *testing.T) {
+		is := is.New(t)
+
+		errs := sendToStalledClient(t, WithWriteTimeout(50*time.Millisecond))
+		is.True(len(errs) > 0)
+		is.True(errors.Is(errs[0], ErrSlowClient)) // deadline should fail with ErrSlowClient
+	})
+
+	t.Run("close slow client", func(t *testing.T) {
+		is := is.New(t)
+
+		errs := sendToStalledClient(t, WithWriteTimeout(50*time.Millisecond), WithCloseSlowClients(true))
+		is.Equal(len(errs), 2)
+		is.True(errors.Is(errs[0], ErrSlowClient))
+		is.Equal(errs[1], errs[0]) // closed stream should fail with the same error
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		is := is.New(t)
+
+		ds, release := New(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), WithWriteTimeout(time.Second))
+		defer release()
+
+		err := ds.Send(RemoveSignals("a"))
+		is.True(errors.Is(err, http.ErrNotSupported)) // recorder doesn't support deadlines
+	})
+}